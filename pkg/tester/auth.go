@@ -0,0 +1,197 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"k8s.io/klog"
+)
+
+// CredentialProvider provides the credentials used to clone the repos.
+// Providers are selected with --git-auth.
+type CredentialProvider interface {
+	// GitAuth returns the auth method used to clone the repos of t, or nil
+	// for anonymous access.
+	GitAuth(t *Tester) (transport.AuthMethod, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(t *Tester) (transport.AuthMethod, error)
+
+func (f CredentialProviderFunc) GitAuth(t *Tester) (transport.AuthMethod, error) {
+	return f(t)
+}
+
+// defaultCredentialProvider is the provider used without --git-auth.
+const defaultCredentialProvider = "env"
+
+var credentialProviders = map[string]CredentialProvider{
+	defaultCredentialProvider: CredentialProviderFunc((*Tester).envGitAuth),
+	"file":                    CredentialProviderFunc((*Tester).fileGitAuth),
+	"github-app":              CredentialProviderFunc((*Tester).githubAppGitAuth),
+	"gcp-secret-manager":      CredentialProviderFunc((*Tester).gcpSecretGitAuth),
+	"vault":                   CredentialProviderFunc((*Tester).vaultGitAuth),
+}
+
+// RegisterCredentialProvider makes a provider available as --git-auth=name.
+// Binaries embedding the tester register their providers before calling
+// Main(), e.g.
+//
+//	func main() {
+//		tester.RegisterCredentialProvider("my-secrets", tester.CredentialProviderFunc(myAuth))
+//		tester.Main()
+//	}
+//
+// It panics if a provider is already registered with name.
+func RegisterCredentialProvider(name string, provider CredentialProvider) {
+	if _, ok := credentialProviders[name]; ok {
+		panic(fmt.Sprintf("credential provider %q already registered", name))
+	}
+	credentialProviders[name] = provider
+}
+
+// credentialProviderNames returns the sorted names of the registered
+// credential providers.
+func credentialProviderNames() []string {
+	names := make([]string, 0, len(credentialProviders))
+	for name := range credentialProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gitAuth returns the auth method of the --git-auth provider.
+func (t *Tester) gitAuth() (transport.AuthMethod, error) {
+	name := t.GitAuth
+	if name == "" {
+		name = defaultCredentialProvider
+	}
+	provider, ok := credentialProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown --git-auth %q, must be one of %s", name, strings.Join(credentialProviderNames(), ", "))
+	}
+	return provider.GitAuth(t)
+}
+
+// envGitAuth returns the auth method configured with the --git-token,
+// --ssh-private-key or --github-app-id flags, or their env variables, or nil
+// when no credentials were configured.
+func (t *Tester) envGitAuth() (transport.AuthMethod, error) {
+	if t.SSHPrivateKey == "" {
+		t.SSHPrivateKey = os.Getenv("SSH_PRIVATE_KEY")
+	}
+	if t.SSHKnownHosts == "" {
+		t.SSHKnownHosts = os.Getenv("SSH_KNOWN_HOSTS")
+	}
+
+	if t.GitToken == "" {
+		t.GitToken = os.Getenv("GIT_TOKEN")
+	}
+
+	if t.SSHPrivateKey != "" && t.GitToken != "" {
+		return nil, fmt.Errorf("--ssh-private-key and --git-token are mutually exclusive")
+	}
+	if t.GithubAppID != "" && (t.SSHPrivateKey != "" || t.GitToken != "") {
+		return nil, fmt.Errorf("--github-app-id is mutually exclusive with --ssh-private-key and --git-token")
+	}
+
+	if t.GithubAppID != "" {
+		return t.githubAppGitAuth()
+	}
+
+	if t.GitToken != "" {
+		return t.tokenAuth(t.GitToken), nil
+	}
+
+	if t.SSHPrivateKey == "" {
+		return nil, nil
+	}
+
+	klog.V(1).Infof("Using SSH private key at %s", t.SSHPrivateKey)
+	auth, err := ssh.NewPublicKeysFromFile("git", t.SSHPrivateKey, os.Getenv("SSH_PRIVATE_KEY_PASSWORD"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh private key %s: %v", t.SSHPrivateKey, err)
+	}
+
+	if t.SSHKnownHosts != "" {
+		callback, err := ssh.NewKnownHostsCallback(t.SSHKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ssh known hosts %s: %v", t.SSHKnownHosts, err)
+		}
+		auth.HostKeyCallback = callback
+	}
+	return auth, nil
+}
+
+// tokenAuth returns the HTTPS auth of token, which is then redacted from
+// the logs.
+func (t *Tester) tokenAuth(token string) transport.AuthMethod {
+	t.GitToken = token
+	klog.V(1).Infof("Using token authentication for %s", t.redact(t.Repo))
+	// the username is ignored by most providers as long as it is not empty
+	return &http.BasicAuth{Username: "git", Password: token}
+}
+
+// fileGitAuth reads the token from --git-secret, a file path, e.g. a
+// mounted Kubernetes secret.
+func (t *Tester) fileGitAuth() (transport.AuthMethod, error) {
+	if t.GitSecret == "" {
+		return nil, fmt.Errorf("--git-auth=file requires --git-secret to be the path of the token file")
+	}
+	data, err := os.ReadFile(t.GitSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git token: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("git token file %s is empty", t.GitSecret)
+	}
+	return t.tokenAuth(token), nil
+}
+
+// githubAppGitAuth authenticates with installation tokens of the
+// --github-app-id GitHub App.
+func (t *Tester) githubAppGitAuth() (transport.AuthMethod, error) {
+	if t.GithubAppID == "" {
+		return nil, fmt.Errorf("--git-auth=github-app requires --github-app-id")
+	}
+	klog.V(1).Infof("Using GitHub App %s installation tokens for %s", t.GithubAppID, t.redact(t.Repo))
+	auth, err := t.newGithubAppAuth()
+	if err != nil {
+		return nil, err
+	}
+	t.githubApp = auth
+	return auth, nil
+}
+
+// gcpSecretGitAuth reads the token from the --git-secret Google Cloud
+// Secret Manager secret.
+func (t *Tester) gcpSecretGitAuth() (transport.AuthMethod, error) {
+	if t.GitSecret == "" {
+		return nil, fmt.Errorf("--git-auth=gcp-secret-manager requires --git-secret to be a secret, projects/PROJECT/secrets/SECRET[/versions/VERSION]")
+	}
+	token, err := readGCPSecret(t.context(), t.GitSecret)
+	if err != nil {
+		return nil, err
+	}
+	return t.tokenAuth(token), nil
+}
+
+// vaultGitAuth reads the token from the --git-secret Vault secret.
+func (t *Tester) vaultGitAuth() (transport.AuthMethod, error) {
+	if t.GitSecret == "" {
+		return nil, fmt.Errorf("--git-auth=vault requires --git-secret to be a secret, PATH#FIELD")
+	}
+	path, field, _ := strings.Cut(t.GitSecret, "#")
+	token, err := readVaultSecret(t.context(), path, field)
+	if err != nil {
+		return nil, err
+	}
+	return t.tokenAuth(token), nil
+}
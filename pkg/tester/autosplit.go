@@ -0,0 +1,86 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// serialSpecs matches the specs that --auto-split runs on a single node.
+const serialSpecs = `\[Serial\]|\[Disruptive\]`
+
+// errNoSpecs is returned by the load of a suite pass that has no specs to
+// run.
+var errNoSpecs = errors.New("no specs to run")
+
+// autoSplitPasses returns the --auto-split passes: the parallel specs with
+// --parallel nodes, then the serial ones on a single node.
+func (t *Tester) autoSplitPasses() []suitePass {
+	return []suitePass{
+		{name: "parallel", load: func() error {
+			t.SkipRegex = joinRegex(t.SkipRegex, serialSpecs)
+			return nil
+		}},
+		{name: "serial", load: func() error {
+			t.Parallel = 1
+			if t.FocusRegex == "" {
+				t.FocusRegex = serialSpecs
+				return nil
+			}
+			// ginkgo runs the specs matching any of its focus flags, so the
+			// serial specs of the focus are listed and focused by name
+			specs, err := t.listSpecs()
+			if err != nil {
+				return fmt.Errorf("--auto-split with a focus: %v", err)
+			}
+			names := serialSpecNames(specs)
+			if len(names) == 0 {
+				return errNoSpecs
+			}
+			focus := make([]string, len(names))
+			for i, name := range names {
+				focus[i] = exactFocus(name)
+			}
+			t.FocusRegex = strings.Join(focus, "|")
+			return nil
+		}},
+	}
+}
+
+// joinRegex returns a regex matching what either a or b match, where an
+// empty regex matches nothing.
+func joinRegex(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "|" + b
+}
+
+// serialSpecNames returns the names of the specs that --auto-split runs on
+// a single node.
+func serialSpecNames(specs []spec) []string {
+	serial := regexp.MustCompile(serialSpecs)
+	var names []string
+	for _, s := range specs {
+		if serial.MatchString(s.Name) {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// validateAutoSplit checks --auto-split.
+func (t *Tester) validateAutoSplit() error {
+	switch {
+	case t.RunMode != runModeGinkgo:
+		return fmt.Errorf("--auto-split is not supported in %s run mode", t.RunMode)
+	case len(t.Suite) > 1:
+		return fmt.Errorf("--auto-split can't be used with several --suite")
+	case t.Conformance:
+		return fmt.Errorf("--auto-split can't be used with --conformance, which runs serially")
+	case t.ListTests:
+		return fmt.Errorf("--auto-split can't be used with --list-tests")
+	}
+	return nil
+}
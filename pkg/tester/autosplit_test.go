@@ -0,0 +1,109 @@
+package tester
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestJoinRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		matches []string
+		misses  []string
+	}{
+		{
+			name:    "empty skip",
+			b:       serialSpecs,
+			matches: []string{"[sig-apps] Foo [Serial]", "[sig-node] Bar [Disruptive]"},
+			misses:  []string{"[sig-apps] Foo"},
+		},
+		{
+			name:    "anchored skip",
+			a:       `^\[sig-storage\]`,
+			b:       serialSpecs,
+			matches: []string{"[sig-storage] Foo", "[sig-apps] Foo [Serial]"},
+			misses:  []string{"[sig-apps] Foo", "[sig-apps] [sig-storage] Foo"},
+		},
+		{
+			name:    "alternation",
+			a:       "Slow|Flaky",
+			b:       serialSpecs,
+			matches: []string{"Foo [Slow]", "Foo [Flaky]", "Foo [Serial]"},
+			misses:  []string{"Foo"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			re := regexp.MustCompile(joinRegex(tc.a, tc.b))
+			for _, name := range tc.matches {
+				if !re.MatchString(name) {
+					t.Errorf("%s doesn't match %q", re, name)
+				}
+			}
+			for _, name := range tc.misses {
+				if re.MatchString(name) {
+					t.Errorf("%s matches %q", re, name)
+				}
+			}
+		})
+	}
+}
+
+func TestSerialSpecNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		want  []string
+	}{
+		{
+			name:  "serial and disruptive",
+			specs: []string{"a [Serial]", "b", "c [Disruptive]", "d [Slow]"},
+			want:  []string{"a [Serial]", "c [Disruptive]"},
+		},
+		{
+			name:  "tags in containers",
+			specs: []string{"[sig-apps] [Serial] Foo bar", "[sig-apps] Serial Foo"},
+			want:  []string{"[sig-apps] [Serial] Foo bar"},
+		},
+		{
+			name:  "no serial specs",
+			specs: []string{"a", "b"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var specs []spec
+			for _, name := range tc.specs {
+				specs = append(specs, spec{Name: name})
+			}
+			if got := serialSpecNames(specs); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("serialSpecNames() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAutoSplitPassesWithoutFocus(t *testing.T) {
+	tester := &Tester{Options: Options{Parallel: 4, SkipRegex: "Flaky"}}
+	passes := tester.autoSplitPasses()
+	if len(passes) != 2 {
+		t.Fatalf("got %d passes, want 2", len(passes))
+	}
+
+	if err := passes[0].load(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Flaky|" + serialSpecs; tester.SkipRegex != want || tester.Parallel != 4 {
+		t.Errorf("parallel pass skips %q with %d nodes, want %q with 4", tester.SkipRegex, tester.Parallel, want)
+	}
+
+	tester.SkipRegex = "Flaky"
+	if err := passes[1].load(); err != nil {
+		t.Fatal(err)
+	}
+	if tester.FocusRegex != serialSpecs || tester.SkipRegex != "Flaky" || tester.Parallel != 1 {
+		t.Errorf("serial pass focuses %q skipping %q with %d nodes, want %q skipping Flaky with 1", tester.FocusRegex, tester.SkipRegex, tester.Parallel, serialSpecs)
+	}
+}
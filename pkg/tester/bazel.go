@@ -0,0 +1,101 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	buildSystemAuto  = "auto"
+	buildSystemMake  = "make"
+	buildSystemBazel = "bazel"
+)
+
+// bazelWorkspaceFiles mark the root of a Bazel workspace.
+var bazelWorkspaceFiles = []string{"MODULE.bazel", "WORKSPACE", "WORKSPACE.bazel"}
+
+// buildSystem returns the --build-system, resolving auto against the cloned
+// repo. Repos with a Makefile keep using the --build-cmd, as the kubernetes
+// repo did while it also had a Bazel workspace.
+func (t *Tester) buildSystem() string {
+	if t.BuildSystem != buildSystemAuto {
+		return t.BuildSystem
+	}
+	if _, err := os.Stat(filepath.Join(t.sourceDir(), "Makefile")); err == nil {
+		return buildSystemMake
+	}
+	for _, name := range bazelWorkspaceFiles {
+		if _, err := os.Stat(filepath.Join(t.sourceDir(), name)); err == nil {
+			klog.V(1).Infof("Found %s in the cloned repo, building with bazel", name)
+			return buildSystemBazel
+		}
+	}
+	return buildSystemMake
+}
+
+// buildWithBazel builds the test package targets with bazel and points the
+// test binary paths to their outputs.
+func (t *Tester) buildWithBazel() error {
+	targets := append([]string{t.BazelTestTarget}, t.BazelTargets...)
+	if err := t.runBuildCmd(shellquote.Join(append([]string{"bazel", "build"}, targets...)...)); err != nil {
+		return err
+	}
+
+	outputs, err := t.bazelOutputs(t.BazelTestTarget)
+	if err != nil {
+		return err
+	}
+	if len(outputs) == 0 {
+		return fmt.Errorf("bazel target %s has no outputs", t.BazelTestTarget)
+	}
+	if t.TestBinaryPath == "" {
+		t.e2eTestPath = outputs[0]
+	}
+
+	for _, target := range t.BazelTargets {
+		outputs, err := t.bazelOutputs(target)
+		if err != nil {
+			return err
+		}
+		for _, output := range outputs {
+			switch filepath.Base(output) {
+			case "ginkgo":
+				if t.GinkgoBinary == "" {
+					t.ginkgoPath = output
+				}
+			case "kubectl":
+				t.kubectlPath = output
+			}
+		}
+	}
+	return nil
+}
+
+// bazelOutputs returns the absolute paths of the files built for target.
+func (t *Tester) bazelOutputs(target string) ([]string, error) {
+	cmd := exec.CommandContext(t.context(), "bazel", "cquery", "--output=files", target)
+	cmd.SetDir(t.sourceDir())
+	cmd.SetEnv(t.env...)
+	lines, err := exec.OutputLines(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the outputs of bazel target %s: %v", target, err)
+	}
+	var outputs []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(t.sourceDir(), line)
+		}
+		outputs = append(outputs, line)
+	}
+	return outputs, nil
+}
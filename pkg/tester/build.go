@@ -0,0 +1,106 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// defaultBuildCmd builds the test binaries the way the kubernetes repo does.
+const defaultBuildCmd = `make WHAT="test/e2e/e2e.test vendor/github.com/onsi/ginkgo/v2/ginkgo cmd/kubectl"`
+
+// defaultBuildCmdNoGinkgo is defaultBuildCmd for when ginkgo is not part of
+// the test package.
+const defaultBuildCmdNoGinkgo = `make WHAT="test/e2e/e2e.test cmd/kubectl"`
+
+// buildTestPackage builds the test binaries from the cloned repo.
+// kubectl is optional for repos that don't build it.
+func (t *Tester) buildTestPackage() error {
+	if t.crossBuilding() {
+		klog.Warningf("building the e2e test binary for %s, running it on %s requires emulation such as qemu binfmt_misc", t.testPlatform(), hostPlatform())
+	}
+	switch {
+	case t.buildSystem() == buildSystemBazel:
+		if err := t.buildWithBazel(); err != nil {
+			return err
+		}
+	case t.BuildCmd == "":
+		klog.V(0).Infof("No build command, expecting prebuilt binaries in %s", filepath.Dir(t.e2eTestPath))
+	default:
+		buildCmd := t.BuildCmd
+		if (t.GinkgoBinary != "" || t.GinkgoVersion != "") && buildCmd == defaultBuildCmd {
+			buildCmd = defaultBuildCmdNoGinkgo
+		}
+		if err := t.runBuildCmd(buildCmd); err != nil {
+			return err
+		}
+	}
+
+	if t.TestBinaryPath == "" {
+		if _, err := os.Stat(t.e2eTestPath); err != nil {
+			return fmt.Errorf("failed to find built binary: %v", err)
+		}
+		klog.V(2).Infof("found built binary at %s", t.e2eTestPath)
+	}
+
+	if _, err := os.Stat(t.kubectlPath); err != nil {
+		klog.Warningf("kubectl was not built at %s", t.kubectlPath)
+		t.kubectlPath = ""
+	}
+	return nil
+}
+
+// buildOutDir returns the absolute path of the directory the build command
+// places its binaries in.
+func (t *Tester) buildOutDir() string {
+	if filepath.IsAbs(t.BuildOutDir) {
+		return t.BuildOutDir
+	}
+	return filepath.Join(t.sourceDir(), t.BuildOutDir)
+}
+
+// runBuildCmd runs buildCmd in the cloned repo, teeing its output into
+// build.log in the artifacts dir.
+func (t *Tester) runBuildCmd(buildCmd string) error {
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return err
+	}
+	logPath := t.artifactPath("build.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create build log: %v", err)
+	}
+	defer logFile.Close()
+
+	ctx := t.context()
+	if t.BuildTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.BuildTimeout)
+		defer cancel()
+	}
+
+	klog.V(0).Infof("Building test package in %s with %q, logging to %s", t.sourceDir(), buildCmd, logPath)
+	cmd := exec.RawCommandContext(ctx, buildCmd)
+	cmd.SetDir(t.sourceDir())
+	env := t.env
+	if t.crossBuilding() && envValue(env, "KUBE_BUILD_PLATFORMS") == "" {
+		// ginkgo and kubectl run on the host
+		env = mergeEnv(env, []string{"KUBE_BUILD_PLATFORMS=" + hostPlatform() + " " + t.testPlatform()})
+	}
+	cmd.SetEnv(env...)
+	exec.SetOutput(cmd, io.MultiWriter(os.Stdout, logFile), io.MultiWriter(os.Stderr, logFile))
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && t.context().Err() == nil {
+			return fmt.Errorf("build command %q did not finish within %v", buildCmd, t.BuildTimeout)
+		}
+		return fmt.Errorf("build command %q failed: %v", buildCmd, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package tester
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// bundleSuffix is the extension of the git bundle files accepted as repos.
+const bundleSuffix = ".bundle"
+
+// isBundle reports whether repo is a local or http(s) git bundle file.
+func isBundle(repo string) bool {
+	if u, err := url.Parse(repo); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return strings.HasSuffix(u.Path, bundleSuffix)
+	}
+	return strings.HasSuffix(repo, bundleSuffix)
+}
+
+// unbundle imports the git bundle at repo, downloading it first when it is a
+// URL, into a bare repo in the run dir and returns its path. go-git can't read
+// bundles, but clones and fetches from the bare repo like from any remote.
+func (t *Tester) unbundle(repo string) (string, error) {
+	if _, err := osexec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("cloning bundle %s requires git: %v", t.redact(repo), err)
+	}
+	u, err := url.Parse(repo)
+	remote := err == nil && (u.Scheme == "http" || u.Scheme == "https")
+	bundle := strings.TrimPrefix(repo, "file://")
+	if remote {
+		bundle = u.Path
+	}
+	sum := sha256.Sum256([]byte(repo))
+	name := repoName(bundle) + "-" + hex.EncodeToString(sum[:])[:12]
+	bundlesDir := filepath.Join(t.runDir, "bundles")
+
+	if remote {
+		if t.Offline {
+			return "", offlineError("bundle "+t.redact(repo), "a local bundle file")
+		}
+		bundle = filepath.Join(bundlesDir, name+bundleSuffix)
+		klog.V(0).Infof("Downloading bundle %s", t.redact(repo))
+		if err := downloadFile(t.context(), repo, bundle, 0644); err != nil {
+			return "", fmt.Errorf("failed to download bundle: %v", t.redact(err.Error()))
+		}
+	}
+	if _, err := os.Stat(bundle); err != nil {
+		return "", fmt.Errorf("failed to find bundle: %v", err)
+	}
+
+	// the bundle may have changed since the last run, import it from scratch
+	dir := filepath.Join(bundlesDir, name+".git")
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	klog.V(0).Infof("Importing bundle %s into %s", t.redact(repo), dir)
+	cmd := exec.CommandContext(t.context(), "git", "clone", "--mirror", "--quiet", bundle, dir)
+	lines, err := exec.CombinedOutputLines(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to import bundle %s: %v: %s", t.redact(repo), err, strings.Join(lines, "\n"))
+	}
+	return dir, nil
+}
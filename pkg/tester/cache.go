@@ -0,0 +1,79 @@
+package tester
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/klog"
+)
+
+// updateCache creates or updates a bare mirror of url in the cache dir and
+// returns its path, so that workspaces can be cloned locally from it.
+func (t *Tester) updateCache(url string, auth transport.AuthMethod) (string, error) {
+	sum := sha256.Sum256([]byte(url))
+	dir := filepath.Join(t.CacheDir, repoName(url)+"-"+hex.EncodeToString(sum[:])[:12]+".git")
+
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		klog.V(0).Infof("Creating clone cache for %s in %s", t.redact(url), dir)
+		repo, err = git.PlainInit(dir, true)
+		if err == nil {
+			_, err = repo.CreateRemote(&config.RemoteConfig{
+				Name:  git.DefaultRemoteName,
+				URLs:  []string{url},
+				Fetch: []config.RefSpec{"+refs/heads/*:refs/heads/*"},
+			})
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open clone cache %s: %v", dir, err)
+	}
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get remote of clone cache %s: %v", dir, err)
+	}
+
+	klog.V(0).Infof("Updating clone cache %s", dir)
+	err = retry(t.context(), t.CloneRetries, t.CloneRetryInterval, isTransientGitError, func() error {
+		return repo.FetchContext(t.context(), &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			Auth:       auth,
+			Tags:       git.AllTags,
+			Progress:   t.gitProgress("update cache " + repoName(url)),
+			Force:      true,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to update clone cache for %s: %v", t.redact(url), t.redact(err.Error()))
+	}
+
+	// local clones of the mirror check out its HEAD, which must follow the
+	// default branch of the remote
+	branch, err := remoteDefaultBranch(t.context(), remote, auth)
+	if err != nil {
+		return "", err
+	}
+	head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch))
+	if err := repo.Storer.SetReference(head); err != nil {
+		return "", fmt.Errorf("failed to set HEAD of clone cache %s: %v", dir, err)
+	}
+	return dir, nil
+}
+
+// useUpstreamRemote points origin of a repo cloned from the cache back to url.
+func useUpstreamRemote(repo *git.Repository, url string) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	cfg.Remotes[git.DefaultRemoteName].URLs = []string{url}
+	return repo.SetConfig(cfg)
+}
@@ -0,0 +1,154 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// pollInterval is how often cluster checks are retried until they pass.
+const pollInterval = 5 * time.Second
+
+// checkCluster verifies that the kubeconfig is valid and that the API server
+// answers /version, retrying until --preflight-timeout.
+func (t *Tester) checkCluster() error {
+	ctx, cancel := context.WithTimeout(t.context(), t.PreflightTimeout)
+	defer cancel()
+
+	server, err := t.kubeconfigServer(ctx)
+	if err != nil {
+		return fmt.Errorf("invalid kubeconfig %s: %v", t.kubeconfigPath, err)
+	}
+	klog.V(0).Infof("Checking that the API server %s is reachable", server)
+
+	err = poll(ctx, func() error {
+		_, err := t.kubectlOutput(ctx, "get", "--raw", "/version")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("API server %s is not reachable after %v: %v", server, t.PreflightTimeout, err)
+	}
+
+	return nil
+}
+
+// waitForNodes polls the cluster until --wait-for-nodes nodes are Ready, for
+// deployers that return before all the nodes joined the cluster.
+func (t *Tester) waitForNodes() error {
+	ctx, cancel := context.WithTimeout(t.context(), t.NodeReadyTimeout)
+	defer cancel()
+
+	klog.V(0).Infof("Waiting up to %v for %d nodes to be Ready", t.NodeReadyTimeout, t.WaitForNodes)
+	last := -1
+	err := poll(ctx, func() error {
+		ready, _, err := t.readyNodes(ctx)
+		if err != nil {
+			return err
+		}
+		if ready != last {
+			klog.V(0).Infof("%d of %d nodes are Ready", ready, t.WaitForNodes)
+			last = ready
+		}
+		if ready < t.WaitForNodes {
+			return fmt.Errorf("%d of the %d required nodes are Ready", ready, t.WaitForNodes)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("nodes not ready after %v: %v", t.NodeReadyTimeout, err)
+	}
+	return nil
+}
+
+// kubeconfigServer returns the API server of the current context of the
+// kubeconfig, failing if the kubeconfig doesn't parse or has no current
+// context.
+func (t *Tester) kubeconfigServer(ctx context.Context) (string, error) {
+	out, err := t.kubectlOutput(ctx, "config", "view", "--minify", "--output=json")
+	if err != nil {
+		return "", err
+	}
+	var config struct {
+		Clusters []struct {
+			Cluster struct {
+				Server string `json:"server"`
+			} `json:"cluster"`
+		} `json:"clusters"`
+	}
+	if err := json.Unmarshal(out, &config); err != nil {
+		return "", fmt.Errorf("failed to parse kubectl config view: %v", err)
+	}
+	if len(config.Clusters) == 0 || config.Clusters[0].Cluster.Server == "" {
+		return "", fmt.Errorf("no cluster server in the current context")
+	}
+	return config.Clusters[0].Cluster.Server, nil
+}
+
+// readyNodes returns the number of nodes whose Ready condition is True, and
+// the number of nodes.
+func (t *Tester) readyNodes(ctx context.Context) (ready, total int, err error) {
+	out, err := t.kubectlOutput(ctx, "get", "nodes", "--output=json")
+	if err != nil {
+		return 0, 0, err
+	}
+	var nodes struct {
+		Items []struct {
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &nodes); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse nodes: %v", err)
+	}
+	for _, node := range nodes.Items {
+		for _, c := range node.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				ready++
+			}
+		}
+	}
+	return ready, len(nodes.Items), nil
+}
+
+// kubectlOutput runs kubectl against the cluster under test and returns its
+// stdout. The error includes the stderr of kubectl.
+func (t *Tester) kubectlOutput(ctx context.Context, args ...string) ([]byte, error) {
+	args = append([]string{"--kubeconfig=" + t.kubeconfigPath, "--request-timeout=10s"}, args...)
+	cmd := exec.CommandContext(ctx, t.kubectl(), args...)
+	var stderr strings.Builder
+	cmd.SetStderr(&stderr)
+	out, err := exec.Output(cmd)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// poll calls fn every pollInterval until it succeeds or ctx is done, and
+// then returns the last error of fn.
+func poll(ctx context.Context, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		klog.V(2).Infof("retrying in %v: %v", pollInterval, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(pollInterval):
+		}
+	}
+}
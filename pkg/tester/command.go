@@ -0,0 +1,74 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// testCommand is a resolved invocation of the test process.
+type testCommand struct {
+	path string
+	args []string
+	// dir is the working directory, empty for the current one
+	dir string
+}
+
+// command returns the exec.Cmd running tc with env. Its output is inherited
+// and also copied to log.
+func (tc *testCommand) command(env []string, log io.Writer) exec.Cmd {
+	cmd := exec.Command(tc.path, tc.args...)
+	if tc.dir != "" {
+		cmd.SetDir(tc.dir)
+	}
+	cmd.SetEnv(env...)
+	exec.SetOutput(cmd, io.MultiWriter(os.Stdout, log), io.MultiWriter(os.Stderr, log))
+	return cmd
+}
+
+// testCommand returns the invocation of the test process for the run mode.
+func (t *Tester) testCommand() (*testCommand, error) {
+	switch t.RunMode {
+	case runModeGoTest:
+		return t.goTestCommand()
+	case runModeCommand:
+		return t.customTestCommand()
+	}
+	return t.ginkgoCommand()
+}
+
+// providerArgs returns the e2e framework arguments describing the cloud
+// provider of the cluster.
+func (t *Tester) providerArgs() []string {
+	var args []string
+	for _, arg := range []struct{ name, value string }{
+		{"provider", t.Provider},
+		{"gce-project", t.GCEProject},
+		{"gce-zone", t.GCEZone},
+		{"gce-region", t.GCERegion},
+		{"cluster-tag", t.ClusterTag},
+		{"cloud-config-file", t.CloudConfigFile},
+	} {
+		if arg.value != "" {
+			args = append(args, "--"+arg.name+"="+arg.value)
+		}
+	}
+	return args
+}
+
+// createTestLog creates e2e.log in the artifacts dir, which records the
+// output of the test process next to what the CI system captured.
+func createTestLog() (*os.File, error) {
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(artifacts.BaseDir(), "e2e.log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test log: %v", err)
+	}
+	return f, nil
+}
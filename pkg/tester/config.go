@@ -0,0 +1,97 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/pflag"
+	"k8s.io/klog"
+	"sigs.k8s.io/yaml"
+)
+
+// applyConfig sets the flags of fs that were not given on the command line
+// from the config file at path, so that command line flags win.
+func applyConfig(fs *pflag.FlagSet, path string) error {
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("%s: unknown flag %q", path, name)
+		}
+		if f.Changed {
+			klog.V(1).Infof("--%s is set on the command line, ignoring its value in %s", name, path)
+			continue
+		}
+		for _, value := range config[name] {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("%s: invalid value for %q: %v", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readConfig reads a config file mapping flag names, without the leading
+// dashes, to their values. Lists set repeatable flags once per item. Files
+// ending in .yaml or .yml are parsed as YAML, others as JSON.
+func readConfig(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	var raw map[string]interface{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return configValues(path, raw)
+}
+
+// configValues returns the values of the flags of a parsed config file as
+// flag values.
+func configValues(path string, raw map[string]interface{}) (map[string][]string, error) {
+	config := map[string][]string{}
+	for name, value := range raw {
+		// e.g. a YAML key without a value or list items
+		if value == nil {
+			config[name] = nil
+			continue
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			switch v := item.(type) {
+			case string:
+				config[name] = append(config[name], v)
+			case bool:
+				config[name] = append(config[name], strconv.FormatBool(v))
+			case float64:
+				config[name] = append(config[name], strconv.FormatFloat(v, 'f', -1, 64))
+			default:
+				return nil, fmt.Errorf("%s: unsupported value for %q, must be a string, number, bool or a list of them", path, name)
+			}
+		}
+	}
+	return config, nil
+}
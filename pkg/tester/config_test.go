@@ -0,0 +1,165 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/octago/sflags/gen/gpflag"
+	"github.com/spf13/pflag"
+)
+
+func TestReadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name: "yaml scalars",
+			file: "config.yaml",
+			data: `# comment
+repo: https://github.com/org/repo
+parallel: 4
+junit: false
+focus-regex: '\[sig-apps\] it''s # not a comment'
+skip-regex: "a\tb" # comment
+`,
+			want: map[string][]string{
+				"repo":        {"https://github.com/org/repo"},
+				"parallel":    {"4"},
+				"junit":       {"false"},
+				"focus-regex": {`\[sig-apps\] it's # not a comment`},
+				"skip-regex":  {"a\tb"},
+			},
+		},
+		{
+			name: "yaml lists",
+			file: "config.yml",
+			data: `env:
+  - A=1
+  - B=2
+pre-test-cmd: [make deps, make tools]
+post-test-cmd:
+`,
+			want: map[string][]string{
+				"env":           {"A=1", "B=2"},
+				"pre-test-cmd":  {"make deps", "make tools"},
+				"post-test-cmd": nil,
+			},
+		},
+		{
+			name: "json",
+			file: "config.json",
+			data: `{"repo": "https://github.com/org/repo", "parallel": 4, "junit": true, "env": ["A=1", "B=2"]}`,
+			want: map[string][]string{
+				"repo":     {"https://github.com/org/repo"},
+				"parallel": {"4"},
+				"junit":    {"true"},
+				"env":      {"A=1", "B=2"},
+			},
+		},
+		{
+			name:    "nested yaml value",
+			file:    "config.yaml",
+			data:    "repo:\n  url: https://github.com/org/repo\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			file:    "config.yaml",
+			data:    "repo: [unclosed\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			file:    "config.json",
+			data:    `{"repo": `,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readConfig(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("readConfig() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readConfig() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("readConfig() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "focus: from-config\nskip: from-config\nenv:\n  - A=1\n  - B=2\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	focus := fs.String("focus", "", "")
+	skip := fs.String("skip", "", "")
+	env := fs.StringArray("env", nil, "")
+	if err := fs.Parse([]string{"--skip=from-flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, path); err != nil {
+		t.Fatalf("applyConfig() failed: %v", err)
+	}
+	if *focus != "from-config" || *skip != "from-flag" || !reflect.DeepEqual(*env, []string{"A=1", "B=2"}) {
+		t.Errorf("got focus %q, skip %q and env %q", *focus, *skip, *env)
+	}
+
+	if err := os.WriteFile(path, []byte("unknown: value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, path); err == nil {
+		t.Error("applyConfig() succeeded with an unknown flag")
+	}
+}
+
+func TestApplyConfigTesterFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "junit: false\njunit-report-prefix: serial_\nparallel: 4\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tester := NewDefaultTester()
+	fs, err := gpflag.Parse(tester)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"--junit=true", "--junit-report-prefix=flag_"}); err != nil {
+		t.Fatalf("failed to parse the junit flags: %v", err)
+	}
+	if !tester.JUnit || tester.JUnitReportPrefix != "flag_" {
+		t.Errorf("got junit %v and junit report prefix %q", tester.JUnit, tester.JUnitReportPrefix)
+	}
+
+	fs, err = gpflag.Parse(tester)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, path); err != nil {
+		t.Fatalf("applyConfig() failed: %v", err)
+	}
+	if tester.JUnit || tester.JUnitReportPrefix != "serial_" || tester.Parallel != 4 {
+		t.Errorf("got junit %v, junit report prefix %q and parallel %d", tester.JUnit, tester.JUnitReportPrefix, tester.Parallel)
+	}
+}
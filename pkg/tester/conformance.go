@@ -0,0 +1,77 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+const (
+	conformanceFocus = `\[Conformance\]`
+	// conformanceSkip matches the specs excluded from conformance runs by
+	// sonobuoy, which would disrupt the other specs.
+	conformanceSkip = `\[Disruptive\]|NoExecuteTaintManager`
+)
+
+// applyConformance sets the test selection and reporting required for
+// conformance submissions, failing on flags that contradict them.
+func (t *Tester) applyConformance() error {
+	switch {
+	case t.RunMode != runModeGinkgo:
+		return fmt.Errorf("--conformance requires the %s run mode", runModeGinkgo)
+	case t.FocusRegex != "" && t.FocusRegex != conformanceFocus:
+		return fmt.Errorf("--conformance runs the specs matching %q, it can't be used with --focus-regex", conformanceFocus)
+	case t.LabelFilter != "":
+		return fmt.Errorf("--conformance can't be used with --label-filter")
+	case t.Parallel != 1:
+		return fmt.Errorf("--conformance requires --parallel=1")
+	case t.JUnitReportPrefix != "" || len(t.Suite) > 1:
+		return fmt.Errorf("--conformance requires the junit_01.xml report name, it can't be used with --junit-report-prefix or several --suite")
+	case t.ShardCount > 1:
+		return fmt.Errorf("--conformance can't be sharded")
+	}
+	t.FocusRegex = conformanceFocus
+	if t.SkipRegex == "" {
+		t.SkipRegex = conformanceSkip
+	}
+	t.JUnit = true
+	return nil
+}
+
+// collectConformanceResults copies e2e.log and junit_01.xml into the
+// conformance dir of the artifacts, the layout of a cncf/k8s-conformance
+// submission.
+func collectConformanceResults() error {
+	dir := filepath.Join(artifacts.BaseDir(), "conformance")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for _, name := range []string{"e2e.log", "junit_01.xml"} {
+		if err := copyFile(filepath.Join(artifacts.BaseDir(), name), filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to collect conformance results: %v", err)
+		}
+	}
+	klog.V(0).Infof("Conformance results are in %s", dir)
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,54 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"os/signal"
+	"runtime"
+
+	"k8s.io/klog"
+)
+
+// debugShell starts an interactive shell in the checkout dir, with the test
+// env, $KUBECONFIG and the test binaries exported, for the developer to
+// inspect the cluster after a failure. It returns when the shell exits, and
+// is skipped when stdin is not a terminal.
+func (t *Tester) debugShell() {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		klog.V(0).Infof("Not starting the debug shell, stdin is not a terminal")
+		return
+	}
+
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+		if runtime.GOOS == "windows" {
+			shell = "cmd.exe"
+		}
+	}
+	env := mergeEnv(t.env, []string{
+		"KUBECONFIG=" + t.kubeconfigPath,
+		"E2E_TEST=" + t.e2eTestPath,
+		"GINKGO=" + t.ginkgoPath,
+		"KUBECTL=" + t.kubectl(),
+	})
+
+	fmt.Fprintf(os.Stderr, "\nThe tests failed, starting %s in %s to debug them. Exit the shell to continue.\n", shell, t.sourceDir())
+	fmt.Fprintf(os.Stderr, "Rerun a spec with: $GINKGO --focus='SPEC' $E2E_TEST -- --kubeconfig=$KUBECONFIG\n\n")
+
+	// the interrupts typed in the shell reach the tester as well
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	cmd := osexec.Command(shell)
+	cmd.Dir = t.sourceDir()
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		klog.V(1).Infof("debug shell exited: %v", err)
+	}
+}
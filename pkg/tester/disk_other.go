@@ -0,0 +1,11 @@
+//go:build !unix
+
+package tester
+
+func freeDiskSpace(path string) (int64, error) {
+	return 0, errDiskUsageUnsupported
+}
+
+func diskUsage(path string) (used, free int64, err error) {
+	return 0, 0, errDiskUsageUnsupported
+}
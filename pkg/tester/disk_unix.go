@@ -0,0 +1,25 @@
+//go:build unix
+
+package tester
+
+import (
+	"syscall"
+)
+
+// freeDiskSpace returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func freeDiskSpace(path string) (int64, error) {
+	_, free, err := diskUsage(path)
+	return free, err
+}
+
+// diskUsage returns the bytes used on, and available to unprivileged users
+// on, the filesystem holding path.
+func diskUsage(path string) (used, free int64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	bsize := int64(stat.Bsize)
+	return (int64(stat.Blocks) - int64(stat.Bfree)) * bsize, int64(stat.Bavail) * bsize, nil
+}
@@ -0,0 +1,75 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// httpGet fetches url, failing on non 2xx responses. The caller must close
+// the returned body.
+func httpGet(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// fetchString returns the trimmed body of url.
+func fetchString(ctx context.Context, url string) (string, error) {
+	body, err := httpGet(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", url, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// downloadFile downloads url to dest with the given file mode.
+func downloadFile(ctx context.Context, url, dest string, mode os.FileMode) error {
+	klog.V(1).Infof("Downloading %s to %s", url, dest)
+	body, err := httpGet(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	// download next to dest so that a partial download never replaces it
+	tmp := dest + ".download"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
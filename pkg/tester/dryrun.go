@@ -0,0 +1,28 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// printDryRun writes the test invocation, with credentials redacted, to w.
+func (t *Tester) printDryRun(w io.Writer) error {
+	tc, err := t.testCommand()
+	if err != nil {
+		return err
+	}
+
+	dir := tc.dir
+	if dir == "" {
+		dir = "(current directory)"
+	}
+	fmt.Fprintf(w, "# working directory: %s\n", dir)
+	fmt.Fprintln(w, "# environment:")
+	for _, kv := range t.redactAll(t.env) {
+		fmt.Fprintf(w, "#   %s\n", kv)
+	}
+	fmt.Fprintln(w, shellquote.Join(t.redactAll(append([]string{tc.path}, tc.args...))...))
+	return nil
+}
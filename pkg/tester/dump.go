@@ -0,0 +1,55 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// kubectl returns the kubectl binary to use, falling back to the one on PATH.
+func (t *Tester) kubectl() string {
+	if t.kubectlPath != "" {
+		return t.kubectlPath
+	}
+	return "kubectl"
+}
+
+// dumpClusterState makes a best effort to collect the cluster state into
+// the artifacts dir so that test failures can be debugged.
+func (t *Tester) dumpClusterState() {
+	dumpDir := filepath.Join(artifacts.BaseDir(), "cluster-dump")
+	if err := os.MkdirAll(dumpDir, os.ModePerm); err != nil {
+		klog.Warningf("failed to create cluster dump dir: %v", err)
+		return
+	}
+	klog.V(0).Infof("Dumping cluster state to %s", dumpDir)
+
+	cmd := exec.CommandContext(t.context(), t.kubectl(), "--kubeconfig="+t.kubeconfigPath,
+		"cluster-info", "dump", "--all-namespaces", "--output-directory="+dumpDir)
+	exec.NoOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		klog.Warningf("failed to dump cluster info: %v", err)
+	}
+
+	t.dumpKubectlOutput(filepath.Join(dumpDir, "events.txt"), "get", "events", "--all-namespaces", "-o", "wide")
+	t.dumpKubectlOutput(filepath.Join(dumpDir, "nodes.txt"), "describe", "nodes")
+}
+
+// dumpKubectlOutput writes the output of kubectl args to path.
+func (t *Tester) dumpKubectlOutput(path string, args ...string) {
+	f, err := os.Create(path)
+	if err != nil {
+		klog.Warningf("failed to create %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	cmd := exec.CommandContext(t.context(), t.kubectl(), append([]string{"--kubeconfig=" + t.kubeconfigPath}, args...)...)
+	exec.SetOutput(cmd, f, f)
+	if err := cmd.Run(); err != nil {
+		klog.Warningf("failed to dump kubectl %v: %v", args, err)
+	}
+}
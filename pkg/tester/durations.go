@@ -0,0 +1,85 @@
+package tester
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// slowestSpecs is the number of slowest specs logged after the run.
+const slowestSpecs = 10
+
+// testDuration is an entry of test-durations.json.
+type testDuration struct {
+	Name     string  `json:"name"`
+	Seconds  float64 `json:"seconds"`
+	Result   string  `json:"result"`
+	Duration string  `json:"duration"`
+}
+
+// writeTestDurations writes the duration of every test case of the junit
+// reports to $ARTIFACTS/test-durations.json, slowest first, and logs the
+// slowest ones.
+func (t *Tester) writeTestDurations() error {
+	reports, err := junitReports()
+	if err != nil {
+		return err
+	}
+	// with ginkgo v2 the same specs may be reported both by the e2e binary
+	// and by the ginkgo CLI
+	seen := map[string]bool{}
+	durations := []testDuration{}
+	for _, report := range reports {
+		suites, err := parseJUnit(report)
+		if err != nil {
+			return err
+		}
+		for _, suite := range suites.Suites {
+			for _, tc := range suite.TestCases {
+				if tc.Skipped != nil || seen[tc.Name] {
+					continue
+				}
+				seen[tc.Name] = true
+				result := "passed"
+				if tc.Failure != nil || tc.Error != nil {
+					result = "failed"
+				}
+				d := time.Duration(tc.Time * float64(time.Second)).Round(time.Millisecond)
+				durations = append(durations, testDuration{
+					Name:     tc.Name,
+					Seconds:  tc.Time,
+					Result:   result,
+					Duration: d.String(),
+				})
+			}
+		}
+	}
+	sort.SliceStable(durations, func(i, j int) bool {
+		return durations[i].Seconds > durations[j].Seconds
+	})
+
+	data, err := json.MarshalIndent(durations, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := t.artifactPath("test-durations.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+
+	if len(durations) == 0 {
+		return nil
+	}
+	n := slowestSpecs
+	if len(durations) < n {
+		n = len(durations)
+	}
+	klog.V(0).Infof("Slowest %d of %d specs:", n, len(durations))
+	for _, d := range durations[:n] {
+		klog.V(0).Infof("  %10s  %s", d.Duration, d.Name)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// resolveEnv returns the environment of the test processes: the inherited
+// environment with the --env-file and then the --env entries, after $VAR
+// expansion, and the --env-from-secret entries merged on top.
+func (t *Tester) resolveEnv() ([]string, error) {
+	var entries []string
+	for _, path := range t.EnvFile {
+		fileEntries, err := readEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	entries = append(entries, t.Env...)
+
+	overrides := make([]string, 0, len(entries))
+	for _, kv := range entries {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --env entry %q, must be KEY=VALUE", t.redact(kv))
+		}
+		overrides = append(overrides, key+"="+os.ExpandEnv(value))
+	}
+
+	secretEnv, err := t.resolveSecretEnv()
+	if err != nil {
+		return nil, err
+	}
+	overrides = append(overrides, secretEnv...)
+	if t.runID != "" {
+		overrides = append(overrides, "KUBETEST2_RUN_ID="+t.runID)
+	}
+	t.envOverrides = overrides
+	env := mergeEnv(os.Environ(), overrides)
+	// only the module cache, or a GOPROXY=file:// mirror, can be used
+	if proxy := envValue(env, "GOPROXY"); t.Offline && proxy != "off" && !strings.HasPrefix(proxy, "file://") {
+		env = mergeEnv(env, []string{"GOPROXY=off"})
+	}
+	return env, nil
+}
+
+// mergeEnv returns base with the KEY=VALUE entries of overrides replacing
+// the entries of base with the same key, and the remaining ones appended.
+func mergeEnv(base, overrides []string) []string {
+	index := map[string]int{}
+	merged := make([]string, 0, len(base)+len(overrides))
+	for _, env := range [][]string{base, overrides} {
+		for _, kv := range env {
+			key, _, _ := strings.Cut(kv, "=")
+			if i, ok := index[key]; ok {
+				merged[i] = kv
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
+
+// envValue returns the value of key in env, empty if it is not set.
+func envValue(env []string, key string) string {
+	value := ""
+	for _, kv := range env {
+		if k, v, _ := strings.Cut(kv, "="); k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// readEnvFile reads the KEY=VALUE entries of a dotenv style file. Blank lines
+// and lines starting with # are ignored, an "export " prefix is allowed and
+// values may be wrapped in single or double quotes.
+func readEnvFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %v", err)
+	}
+
+	var entries []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: invalid entry, must be KEY=VALUE", path, i+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		entries = append(entries, key+"="+value)
+	}
+	return entries, nil
+}
@@ -0,0 +1,192 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      []string
+		overrides []string
+		want      []string
+	}{
+		{
+			name:      "overrides replace in place",
+			base:      []string{"A=1", "B=2", "C=3"},
+			overrides: []string{"B=two"},
+			want:      []string{"A=1", "B=two", "C=3"},
+		},
+		{
+			name:      "new keys are appended",
+			base:      []string{"A=1"},
+			overrides: []string{"D=4", "E="},
+			want:      []string{"A=1", "D=4", "E="},
+		},
+		{
+			name:      "last override wins",
+			base:      []string{"A=1"},
+			overrides: []string{"A=2", "A=3"},
+			want:      []string{"A=3"},
+		},
+		{
+			name:      "values containing =",
+			base:      []string{"OPTS=a=b"},
+			overrides: []string{"OPTS=c=d"},
+			want:      []string{"OPTS=c=d"},
+		},
+		{
+			name:      "duplicate base keys",
+			base:      []string{"A=1", "A=2"},
+			overrides: nil,
+			want:      []string{"A=2"},
+		},
+		{
+			name: "empty",
+			want: []string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mergeEnv(tc.base, tc.overrides); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("mergeEnv() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	t.Setenv("KUBETEST2_TEST_BASE", "/base")
+	t.Setenv("KUBETEST2_TEST_OVERRIDDEN", "old")
+
+	tests := []struct {
+		name    string
+		env     []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "expands and overrides",
+			env:  []string{"KUBETEST2_TEST_DIR=$KUBETEST2_TEST_BASE/dir", "KUBETEST2_TEST_OVERRIDDEN=new"},
+			want: []string{"KUBETEST2_TEST_BASE=/base", "KUBETEST2_TEST_DIR=/base/dir", "KUBETEST2_TEST_OVERRIDDEN=new"},
+		},
+		{
+			name: "unset variables expand to nothing",
+			env:  []string{"KUBETEST2_TEST_DIR=${KUBETEST2_TEST_UNSET}x"},
+			want: []string{"KUBETEST2_TEST_DIR=x"},
+		},
+		{
+			name: "empty value",
+			env:  []string{"KUBETEST2_TEST_OVERRIDDEN="},
+			want: []string{"KUBETEST2_TEST_OVERRIDDEN="},
+		},
+		{
+			name:    "missing =",
+			env:     []string{"KUBETEST2_TEST_DIR"},
+			wantErr: true,
+		},
+		{
+			name:    "missing key",
+			env:     []string{"=value"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tester := &Tester{}
+			tester.Env = tc.env
+			env, err := tester.resolveEnv()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("resolveEnv() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveEnv() failed: %v", err)
+			}
+			got := map[string]bool{}
+			for _, kv := range env {
+				got[kv] = true
+			}
+			for _, kv := range tc.want {
+				if !got[kv] {
+					t.Errorf("resolveEnv() doesn't contain %q", kv)
+				}
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "comments and blank lines",
+			data: "# comment\n\nA=1\n  # indented comment\nB=2\n",
+			want: []string{"A=1", "B=2"},
+		},
+		{
+			name: "export prefix and spaces",
+			data: "export A=1\n B = two words \n",
+			want: []string{"A=1", "B=two words"},
+		},
+		{
+			name: "quotes",
+			data: "A=\"quoted # not a comment\"\nB='single'\nC=\"unbalanced'\nD=\"\"\n",
+			want: []string{"A=quoted # not a comment", "B=single", "C=\"unbalanced'", "D="},
+		},
+		{
+			name: "values containing =",
+			data: "OPTS=--a=b --c=d\n",
+			want: []string{"OPTS=--a=b --c=d"},
+		},
+		{
+			name: "windows line endings",
+			data: "A=1\r\nB=2\r\n",
+			want: []string{"A=1", "B=2"},
+		},
+		{
+			name:    "missing =",
+			data:    "A=1\nB\n",
+			wantErr: true,
+		},
+		{
+			name: "empty file",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readEnvFile(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("readEnvFile() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEnvFile() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("readEnvFile() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadEnvFileMissing(t *testing.T) {
+	if _, err := readEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("readEnvFile() succeeded on a missing file")
+	}
+}
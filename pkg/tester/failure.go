@@ -0,0 +1,61 @@
+package tester
+
+import (
+	"errors"
+)
+
+// failureClass tells infrastructure failures apart from test failures, so
+// that CI does not count e.g. a failed clone as a failed test.
+type failureClass string
+
+const (
+	failureInfra   failureClass = "infrastructure"
+	failureTest    failureClass = "test"
+	failureTimeout failureClass = "timeout"
+)
+
+// exitCodes are the exit codes of the tester binary for each failure class.
+var exitCodes = map[failureClass]int{
+	failureTest:    1,
+	failureInfra:   2,
+	failureTimeout: 3,
+}
+
+// classifiedError is an error with its failure class.
+type classifiedError struct {
+	class failureClass
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// classify wraps err with class unless it is nil or already classified.
+func classify(class failureClass, err error) error {
+	var classified *classifiedError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// classOf returns the failure class of err. Test() classifies all test
+// failures, so anything else, e.g. failing to parse flags, is an
+// infrastructure failure.
+func classOf(err error) failureClass {
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class
+	}
+	return failureInfra
+}
+
+// exitCode returns the exit code of the tester binary for err.
+func exitCode(err error) int {
+	return exitCodes[classOf(err)]
+}
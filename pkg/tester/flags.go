@@ -0,0 +1,22 @@
+package tester
+
+import (
+	"strings"
+)
+
+// stringArray is a repeatable flag value that, unlike []string, does not
+// split its values on commas, so it can hold shell commands.
+type stringArray []string
+
+func (s *stringArray) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func (s *stringArray) String() string {
+	return "[" + strings.Join(*s, ", ") + "]"
+}
+
+func (s *stringArray) Type() string {
+	return "stringArray"
+}
@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// flake is an entry of flakes.json: a spec that failed and then passed on
+// one of its --flake-attempts.
+type flake struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Attempts int    `json:"attempts"`
+}
+
+// ginkgoJSONReportPath is where ginkgo v2 writes its JSON report with
+// --json-report, or when --flake-attempts is greater than 1.
+func (t *Tester) ginkgoJSONReportPath() string {
+	return filepath.Join(artifacts.BaseDir(), t.JUnitReportPrefix+"report.json")
+}
+
+// writeFlakeReport writes the specs that needed more than one attempt to
+// pass to $ARTIFACTS/flakes.json and records their number in the metadata.
+func (t *Tester) writeFlakeReport() error {
+	path := t.ginkgoJSONReportPath()
+	report, err := readGinkgoReport(path)
+	if os.IsNotExist(err) {
+		// ginkgo v1, or ginkgo did not get to write its report
+		klog.V(1).Infof("No ginkgo report at %s, not looking for flakes", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	flakes := []flake{}
+	for _, suite := range report {
+		for _, r := range suite.SpecReports {
+			if r.LeafNodeType != "It" || r.State != "passed" || r.NumAttempts < 2 {
+				continue
+			}
+			s := spec{
+				Name: strings.Join(append(r.ContainerHierarchyTexts, r.LeafNodeText), " "),
+				File: r.LeafNodeLocation.FileName,
+				Line: r.LeafNodeLocation.LineNumber,
+			}
+			flakes = append(flakes, flake{Name: s.Name, Location: s.location(), Attempts: r.NumAttempts})
+		}
+	}
+	sort.Slice(flakes, func(i, j int) bool { return flakes[i].Name < flakes[j].Name })
+
+	for _, f := range flakes {
+		klog.Warningf("Flaky spec passed after %d attempts: %s", f.Attempts, f.Name)
+	}
+	out, err := json.MarshalIndent(flakes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.artifactPath("flakes.json"), out, 0644); err != nil {
+		return err
+	}
+	return addMetadata(map[string]string{"flaky-specs": strconv.Itoa(len(flakes))})
+}
@@ -0,0 +1,239 @@
+package tester
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	runModeGinkgo  = "ginkgo"
+	runModeGoTest  = "go-test"
+	runModeCommand = "command"
+)
+
+// runGinkgo runs the acquired e2e.test binary through ginkgo.
+func (t *Tester) runGinkgo() error {
+	tc, err := t.ginkgoCommand()
+	if err != nil {
+		return err
+	}
+	if err := addMetadata(map[string]string{"ginkgo-seed": strconv.FormatInt(t.Seed, 10)}); err != nil {
+		klog.Warningf("failed to write ginkgo seed to metadata: %v", err)
+	}
+	if t.ShardCount > 1 {
+		shardArgs, err := t.shardArgs()
+		if err != nil {
+			return fmt.Errorf("failed to shard specs: %v", err)
+		}
+		tc.args = append(shardArgs, withoutFocus(tc.args)...)
+	}
+
+	log, err := createTestLog()
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	out := io.Writer(log)
+	if progress := t.newSpecProgress(); progress != nil {
+		out = io.MultiWriter(log, progress)
+		defer progress.Close()
+	}
+
+	klog.V(0).Infof("Running ginkgo test as %s %+v", tc.path, t.redactAll(tc.args))
+	var testErr error
+	if t.InCluster {
+		testErr = t.runInCluster(tc, out)
+	} else {
+		testErr = t.runTestCmd(tc.command(t.env, out))
+	}
+
+	if t.FlakeAttempts > 1 {
+		if err := t.writeFlakeReport(); err != nil {
+			klog.Warningf("failed to write flake report: %v", err)
+		}
+	}
+	if t.JSONReport {
+		if err := t.writeReportSummary(); err != nil {
+			klog.Warningf("failed to summarize the ginkgo report: %v", err)
+		}
+	}
+
+	if t.JUnit {
+		if err := validateJUnitReports(); err != nil {
+			if testErr != nil {
+				klog.Warning(err)
+				return testErr
+			}
+			return err
+		}
+	}
+	return testErr
+}
+
+// ginkgoCommand returns the ginkgo invocation that runs the tests.
+func (t *Tester) ginkgoCommand() (*testCommand, error) {
+	e2eTestArgs := []string{
+		"--kubeconfig=" + t.kubeconfigPath,
+		"--ginkgo.skip=" + t.SkipRegex,
+		"--ginkgo.focus=" + t.FocusRegex,
+	}
+	if t.JUnit {
+		e2eTestArgs = append(e2eTestArgs, "--report-dir="+artifacts.BaseDir())
+		if t.JUnitReportPrefix != "" {
+			e2eTestArgs = append(e2eTestArgs, "--report-prefix="+t.JUnitReportPrefix)
+		}
+	}
+	if t.kubectlPath != "" {
+		e2eTestArgs = append(e2eTestArgs, "--kubectl-path="+t.kubectlPath)
+	}
+	e2eTestArgs = append(e2eTestArgs, t.namespaceArgs()...)
+	e2eTestArgs = append(e2eTestArgs, t.providerArgs()...)
+
+	extraGingkoArgs, err := shellquote.Split(t.GinkgoArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --gingko-args: %v", err)
+	}
+	extraTestArgs, err := shellquote.Split(t.TestArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --test-args: %v", err)
+	}
+
+	// pick the seed here rather than leaving it to ginkgo, so it can be
+	// recorded to reproduce the spec order
+	if t.Seed == 0 {
+		t.Seed = time.Now().Unix()
+	}
+
+	// some ginkgo flags and behaviors are not backwards compatible
+	ginkgoVersion := t.ginkgoMajorVersion()
+	switch ginkgoVersion {
+	case "1", "2":
+	case "":
+		klog.Warningf("failed to detect ginkgo version of %s, assuming v2", t.ginkgoPath)
+	default:
+		return nil, fmt.Errorf("unsupported ginkgo version: %s", ginkgoVersion)
+	}
+	if ginkgoVersion == "1" && t.LabelFilter != "" {
+		return nil, fmt.Errorf("--label-filter requires ginkgo v2")
+	}
+	if ginkgoVersion == "1" && t.Repeat > 0 {
+		return nil, fmt.Errorf("--repeat requires ginkgo v2")
+	}
+	if ginkgoVersion == "1" && t.FlakeAttempts > 1 {
+		klog.Warningf("flake detection requires ginkgo v2, not writing flakes.json")
+	}
+	versionedGinkgoArgs, versionedE2ETestArgs := t.versionedArgs(ginkgoVersion)
+	e2eTestArgs = append(e2eTestArgs, versionedE2ETestArgs...)
+
+	ginkgoArgs := append(extraGingkoArgs, versionedGinkgoArgs...)
+	ginkgoArgs = append(ginkgoArgs, t.e2eTestPath, "--")
+	ginkgoArgs = append(ginkgoArgs, e2eTestArgs...)
+	ginkgoArgs = append(ginkgoArgs, extraTestArgs...)
+
+	tc := &testCommand{path: t.ginkgoPath, args: ginkgoArgs}
+	if t.TestWorkdir != "" {
+		tc.dir = t.TestWorkdir
+		if !filepath.IsAbs(tc.dir) {
+			tc.dir = filepath.Join(t.sourceDir(), tc.dir)
+		}
+	}
+	return tc, nil
+}
+
+// ginkgoMajorVersion returns the ginkgo major version
+// empty if not found
+func (t *Tester) ginkgoMajorVersion() string {
+	klog.V(2).Infof("checking ginkgo version ...")
+	cmd := exec.CommandContext(t.context(), t.ginkgoPath, "version")
+	lines, err := exec.OutputLines(cmd)
+	if err != nil || len(lines) != 1 {
+		return ""
+	}
+	// the output is in the format
+	// Ginkgo Version 1.14.0
+	// Ginkgo Version 2.1.4
+	parts := strings.Split(lines[0], " ")
+	if len(parts) != 3 {
+		return ""
+	}
+	vers := strings.Split(parts[2], ".")
+	if len(vers) != 3 {
+		return ""
+	}
+	return vers[0]
+}
+
+// versionedArgs returns the ginkgo CLI arguments and the e2e test arguments
+// whose names or semantics changed between ginkgo major versions.
+func (t *Tester) versionedArgs(majorVersion string) (ginkgoArgs, e2eTestArgs []string) {
+	seed := "--seed=" + strconv.FormatInt(t.Seed, 10)
+	if majorVersion == "1" {
+		// ginkgo v1 enforces the suite timeout from the CLI
+		ginkgoArgs = []string{
+			"--nodes=" + strconv.Itoa(t.Parallel),
+			"--flakeAttempts=" + strconv.Itoa(t.FlakeAttempts),
+			"--timeout=" + t.Timeout.String(),
+			seed,
+		}
+		if t.RandomizeAll {
+			ginkgoArgs = append(ginkgoArgs, "--randomizeAllSpecs")
+		}
+		if t.RandomizeSuites {
+			ginkgoArgs = append(ginkgoArgs, "--randomizeSuites")
+		}
+		if t.UntilItFails {
+			ginkgoArgs = append(ginkgoArgs, "--untilItFails")
+		}
+		return ginkgoArgs, nil
+	}
+
+	ginkgoArgs = []string{
+		"--procs=" + strconv.Itoa(t.Parallel),
+		"--flake-attempts=" + strconv.Itoa(t.FlakeAttempts),
+		seed,
+	}
+	if t.RandomizeAll {
+		ginkgoArgs = append(ginkgoArgs, "--randomize-all")
+	}
+	if t.RandomizeSuites {
+		ginkgoArgs = append(ginkgoArgs, "--randomize-suites")
+	}
+	if t.UntilItFails {
+		ginkgoArgs = append(ginkgoArgs, "--until-it-fails")
+	}
+	if t.Repeat > 0 {
+		ginkgoArgs = append(ginkgoArgs, "--repeat="+strconv.Itoa(t.Repeat))
+	}
+	outputDir := t.JUnit && !t.Conformance
+	if outputDir {
+		// suites that don't honor --report-dir still get a junit report
+		ginkgoArgs = append(ginkgoArgs,
+			"--output-dir="+artifacts.BaseDir(),
+			"--junit-report=junit_"+t.JUnitReportPrefix+"ginkgo.xml",
+		)
+	}
+	if t.FlakeAttempts > 1 || t.JSONReport {
+		// the JSON report records the attempts of every spec
+		report := t.ginkgoJSONReportPath()
+		if outputDir {
+			report = filepath.Base(report)
+		}
+		ginkgoArgs = append(ginkgoArgs, "--json-report="+report)
+	}
+	e2eTestArgs = []string{
+		"--ginkgo.timeout=" + t.Timeout.String(),
+	}
+	if t.LabelFilter != "" {
+		e2eTestArgs = append(e2eTestArgs, "--ginkgo.label-filter="+t.LabelFilter)
+	}
+	return ginkgoArgs, e2eTestArgs
+}
@@ -0,0 +1,62 @@
+package tester
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const ginkgoPackage = "github.com/onsi/ginkgo/v2"
+
+// installGinkgo installs ginkgo with go install into a GOBIN in the run dir,
+// for repos that don't vendor it. The version is --ginkgo-version, or else
+// the one required by the go.mod of the cloned repo, or else the latest.
+func (t *Tester) installGinkgo() error {
+	version := t.GinkgoVersion
+	if version == "" {
+		version = goModVersion(filepath.Join(t.sourceDir(), "go.mod"), ginkgoPackage)
+	}
+	if version == "" {
+		version = "latest"
+	}
+
+	gobin := filepath.Join(t.runDir, "gobin")
+	klog.V(0).Infof("Installing ginkgo %s into %s", version, gobin)
+	cmd := exec.CommandContext(t.context(), "go", "install", ginkgoPackage+"/ginkgo@"+version)
+	cmd.SetEnv(append(t.env, "GOBIN="+gobin)...)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		if t.Offline {
+			return fmt.Errorf("%v: ginkgo %s is not in the Go module cache: %v", offlineError("ginkgo "+version, "--ginkgo-binary"), version, err)
+		}
+		return fmt.Errorf("failed to install ginkgo %s: %v", version, err)
+	}
+	t.ginkgoPath = filepath.Join(gobin, "ginkgo")
+
+	return addMetadata(map[string]string{"ginkgo-version": version})
+}
+
+// goModVersion returns the version of module required by the go.mod file at
+// path, empty if it is not required or the file can't be read.
+func goModVersion(path, module string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// matches both "require module v1.2.3" and the lines of require blocks
+		fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "require "))
+		if len(fields) >= 2 && fields[0] == module && strings.HasPrefix(fields[1], "v") {
+			return fields[1]
+		}
+	}
+	return ""
+}
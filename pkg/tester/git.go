@@ -0,0 +1,431 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// cloneRepo clones t.Repo into the checkout dir and checks out the requested
+// revision, then clones any extra repos next to it.
+func (t *Tester) cloneRepo() error {
+	if t.SkipClone {
+		klog.V(0).Infof("Skipping clone, using the existing source in %s", t.CheckoutDir)
+		if _, err := os.Stat(t.CheckoutDir); err != nil {
+			return fmt.Errorf("--skip-clone requires an existing checkout dir: %v", err)
+		}
+		return nil
+	}
+
+	if err := t.configureGitTransport(); err != nil {
+		return err
+	}
+	auth, err := t.gitAuth()
+	if err != nil {
+		return err
+	}
+
+	repo, err := t.cloneWithMirrors(auth)
+	if err != nil {
+		return err
+	}
+
+	if t.Commit != "" {
+		if err := checkoutRevision(repo, t.Commit, t.sparsePaths()); err != nil {
+			if t.Branch != "" {
+				return fmt.Errorf("%v, only branch %q was cloned", err, t.Branch)
+			}
+			return err
+		}
+		if t.RecurseSubmodules {
+			if err := updateSubmodules(t.context(), repo, auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.PR > 0 {
+		if err := t.checkoutPullRequest(repo, auth); err != nil {
+			return err
+		}
+		if t.RecurseSubmodules {
+			if err := updateSubmodules(t.context(), repo, auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.VerifySignatures != "" {
+		if err := t.verifySignatures(repo); err != nil {
+			return err
+		}
+	}
+
+	if t.MergeTarget != "" {
+		if err := t.mergeTarget(repo, auth); err != nil {
+			return err
+		}
+		if t.RecurseSubmodules {
+			if err := updateSubmodules(t.context(), repo, auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	t.gitCommit = head.Hash().String()
+	if err := t.recordGitVersion(); err != nil {
+		return err
+	}
+
+	for _, extra := range t.ExtraRepos {
+		url, branch, _ := strings.Cut(extra, "#")
+		dir := filepath.Join(filepath.Dir(t.CheckoutDir), repoName(url))
+		var ref plumbing.ReferenceName
+		if branch != "" {
+			ref = plumbing.NewBranchReferenceName(branch)
+		}
+		if _, err := t.clone(url, ref, dir, auth, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordGitVersion logs the tested commit and records it in the artifacts
+// dir and the kubetest2 metadata, since branch tips move.
+func (t *Tester) recordGitVersion() error {
+	klog.V(0).Infof("Testing %s at commit %s", t.redact(t.Repo), t.gitCommit)
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return err
+	}
+	path := t.artifactPath("git-version.txt")
+	if err := os.WriteFile(path, []byte(t.gitCommit+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	meta := map[string]string{"git-commit": t.gitCommit}
+	if t.Tag != "" {
+		meta["git-tag"] = t.Tag
+	}
+	return addMetadata(meta)
+}
+
+// cloneRef returns the branch or tag reference to clone, empty for the
+// remote default branch.
+func (t *Tester) cloneRef() plumbing.ReferenceName {
+	switch {
+	case t.Tag != "":
+		return plumbing.NewTagReferenceName(t.Tag)
+	case t.Branch != "":
+		return plumbing.NewBranchReferenceName(t.Branch)
+	}
+	return ""
+}
+
+// clone clones url into dir, restricted to the branch or tag ref when it is
+// not empty, and checks out only the sparse paths when any are given. An
+// existing clone of url in dir is synced instead.
+func (t *Tester) clone(url string, ref plumbing.ReferenceName, dir string, auth transport.AuthMethod, sparse []string) (*git.Repository, error) {
+	if isBundle(url) {
+		bare, err := t.unbundle(url)
+		if err != nil {
+			return nil, err
+		}
+		url = bare
+	}
+	if _, err := os.Stat(dir); err == nil && t.SyncStrategy == syncStrategyFresh {
+		klog.V(0).Infof("Removing %s to clone %s afresh", dir, t.redact(url))
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %v", dir, err)
+		}
+	}
+	if repo, err := git.PlainOpen(dir); err == nil {
+		klog.V(0).Infof("Reusing existing clone of %s in %s", t.redact(url), dir)
+		return repo, t.syncClone(repo, url, ref, auth, sparse)
+	} else if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("failed to open existing repo in %s: %v", dir, err)
+	}
+
+	opts := &git.CloneOptions{
+		URL:      url,
+		Auth:     auth,
+		Progress: t.gitProgress("clone " + repoName(url)),
+	}
+	if t.CacheDir != "" {
+		mirror, err := t.updateCache(url, auth)
+		if err != nil {
+			return nil, err
+		}
+		opts.URL = mirror
+		opts.Auth = nil
+	}
+	if ref != "" {
+		opts.ReferenceName = ref
+		opts.SingleBranch = true
+	}
+	if t.RecurseSubmodules {
+		opts.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+	}
+	// sparse checkouts, and tags which may point to a tag object rather than
+	// a commit, are checked out once the clone is complete
+	opts.NoCheckout = len(sparse) > 0 || ref.IsTag()
+
+	klog.V(0).Infof("Cloning %s (ref: %q) into %s", t.redact(url), ref.Short(), dir)
+	var repo *git.Repository
+	err := retry(t.context(), t.CloneRetries, t.CloneRetryInterval, isTransientGitError, func() error {
+		var err error
+		repo, err = git.PlainCloneContext(t.context(), dir, false, opts)
+		return err
+	})
+	if errors.Is(err, git.NoMatchingRefSpecError{}) || errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, t.missingRefError(opts.URL, ref, opts.Auth)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone repo %s: %v", t.redact(url), t.redact(err.Error()))
+	}
+	if t.CacheDir != "" {
+		if err := useUpstreamRemote(repo, url); err != nil {
+			return nil, fmt.Errorf("failed to set remote of %s: %v", dir, err)
+		}
+	}
+	if !opts.NoCheckout {
+		return repo, nil
+	}
+
+	checkoutOpts := &git.CheckoutOptions{Force: true}
+	if ref.IsTag() {
+		hash, err := resolveTag(repo, ref.Short())
+		if err != nil {
+			return nil, err
+		}
+		checkoutOpts.Hash = hash
+	} else {
+		head, err := repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve HEAD: %v", err)
+		}
+		checkoutOpts.Branch = head.Name()
+	}
+	if err := checkout(repo, checkoutOpts, sparse); err != nil {
+		return nil, fmt.Errorf("failed to checkout %s: %v", ref.Short(), err)
+	}
+	if t.RecurseSubmodules {
+		if err := updateSubmodules(t.context(), repo, auth); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// syncClone fetches origin and hard resets the worktree of an existing
+// clone to the branch or tag ref, or to the tip of the remote default branch.
+func (t *Tester) syncClone(repo *git.Repository, url string, ref plumbing.ReferenceName, auth transport.AuthMethod, sparse []string) error {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("failed to get remote of existing repo: %v", err)
+	}
+	if urls := remote.Config().URLs; len(urls) == 0 || urls[0] != url {
+		return fmt.Errorf("existing repo is a clone of %v, not %s, use --sync-strategy=%s to clone again", t.redactAll(urls), t.redact(url), syncStrategyFresh)
+	}
+
+	refSpecs := []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"}
+	if ref.IsTag() {
+		refSpecs = append(refSpecs, "+refs/tags/*:refs/tags/*")
+	}
+	err = retry(t.context(), t.CloneRetries, t.CloneRetryInterval, isTransientGitError, func() error {
+		return repo.FetchContext(t.context(), &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs:   refSpecs,
+			Auth:       auth,
+			Progress:   t.gitProgress("fetch " + repoName(url)),
+			Force:      true,
+		})
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch %s: %v", t.redact(url), t.redact(err.Error()))
+	}
+
+	var hash plumbing.Hash
+	if ref.IsTag() {
+		if _, err := repo.Tag(ref.Short()); errors.Is(err, git.ErrTagNotFound) {
+			return t.missingRefError(url, ref, auth)
+		}
+		if hash, err = resolveTag(repo, ref.Short()); err != nil {
+			return err
+		}
+	} else {
+		branch := ref.Short()
+		if branch == "" {
+			if branch, err = remoteDefaultBranch(t.context(), remote, auth); err != nil {
+				return err
+			}
+		}
+		ref = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, branch)
+		branchRef, err := repo.Reference(ref, true)
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return t.missingRefError(url, plumbing.NewBranchReferenceName(branch), auth)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find branch %q: %v", branch, err)
+		}
+		hash = branchRef.Hash()
+	}
+
+	if err := t.syncWorktree(repo, ref, hash, sparse); err != nil {
+		return err
+	}
+
+	if t.RecurseSubmodules {
+		return updateSubmodules(t.context(), repo, auth)
+	}
+	return nil
+}
+
+// remoteDefaultBranch returns the branch the HEAD of remote points to.
+func remoteDefaultBranch(ctx context.Context, remote *git.Remote, auth transport.AuthMethod) (string, error) {
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+	if err != nil {
+		return "", fmt.Errorf("failed to list remote refs: %v", err)
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			return ref.Target().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("failed to find the default branch of the remote")
+}
+
+// resolveTag returns the commit the tag name points to, peeling annotated
+// tags.
+func resolveTag(repo *git.Repository, name string) (plumbing.Hash, error) {
+	ref, err := repo.Tag(name)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to find tag %q: %v", name, err)
+	}
+	hash := ref.Hash()
+	for {
+		if _, err := repo.CommitObject(hash); err == nil {
+			return hash, nil
+		}
+		tag, err := repo.TagObject(hash)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to resolve tag %q: %v", name, err)
+		}
+		if tag.TargetType != plumbing.CommitObject && tag.TargetType != plumbing.TagObject {
+			return plumbing.ZeroHash, fmt.Errorf("tag %q points to a %s, not a commit", name, tag.TargetType)
+		}
+		hash = tag.Target
+	}
+}
+
+// checkoutRevision resolves rev and checks it out as a detached HEAD.
+func checkoutRevision(repo *git.Repository, rev string, sparse []string) error {
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return fmt.Errorf("failed to resolve revision %q: %v", rev, err)
+	}
+
+	klog.V(0).Infof("Checking out revision %s", hash)
+	if err := checkout(repo, &git.CheckoutOptions{Hash: *hash}, sparse); err != nil {
+		return fmt.Errorf("failed to checkout revision %s: %v", hash, err)
+	}
+	return nil
+}
+
+// checkout checks out opts in the worktree of repo, materializing only the
+// sparse paths when any are given. It falls back to a full checkout when the
+// sparse one fails.
+func checkout(repo *git.Repository, opts *git.CheckoutOptions, sparse []string) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %v", err)
+	}
+	if len(sparse) > 0 {
+		sparseOpts := *opts
+		sparseOpts.SparseCheckoutDirectories = sparse
+		err := wt.Checkout(&sparseOpts)
+		if err == nil {
+			return nil
+		}
+		klog.Warningf("sparse checkout of %v failed, falling back to a full checkout: %v", sparse, err)
+		opts.Force = true
+	}
+	return wt.Checkout(opts)
+}
+
+// sparsePaths returns the cleaned --sparse-paths.
+func (t *Tester) sparsePaths() []string {
+	var paths []string
+	for _, p := range t.SparsePaths {
+		if p = path.Clean(strings.Trim(p, "/")); p != "." {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// updateSubmodules syncs the submodules of repo with the commit checked out
+// in its worktree.
+func updateSubmodules(ctx context.Context, repo *git.Repository, auth transport.AuthMethod) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %v", err)
+	}
+	submodules, err := wt.Submodules()
+	if err != nil {
+		return fmt.Errorf("failed to list submodules: %v", err)
+	}
+	err = submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              auth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update submodules: %v", err)
+	}
+	return nil
+}
+
+// isTransientGitError reports whether err may go away when the operation is retried.
+func isTransientGitError(err error) bool {
+	switch {
+	case errors.Is(err, git.NoErrAlreadyUpToDate),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, transport.ErrEmptyRemoteRepository),
+		errors.Is(err, git.ErrRepositoryAlreadyExists),
+		errors.Is(err, plumbing.ErrReferenceNotFound),
+		errors.Is(err, git.NoMatchingRefSpecError{}):
+		return false
+	}
+	return true
+}
+
+// repoName returns the name of the repository referenced by url, e.g.
+// "kubernetes" for both https://github.com/kubernetes/kubernetes.git and
+// git@github.com:kubernetes/kubernetes.git.
+func repoName(url string) string {
+	url = strings.TrimSuffix(strings.TrimSuffix(strings.TrimRight(url, "/"), ".git"), bundleSuffix)
+	if i := strings.LastIndex(url, ":"); i > strings.LastIndex(url, "/") {
+		url = url[i+1:]
+	}
+	return path.Base(url)
+}
@@ -0,0 +1,173 @@
+package tester
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"k8s.io/klog"
+)
+
+// tokenRefreshMargin is how long before its expiry an installation token is
+// replaced, so that it doesn't expire in the middle of a request.
+const tokenRefreshMargin = 5 * time.Minute
+
+// githubAppAuth authenticates git over HTTPS with installation tokens of a
+// GitHub App, creating a new one whenever the current one is about to
+// expire, e.g. during a long clone with submodules.
+type githubAppAuth struct {
+	apiEndpoint    string
+	appID          string
+	installationID string
+	key            *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var _ githttp.AuthMethod = &githubAppAuth{}
+
+// newGithubAppAuth loads the private key of the GitHub App and creates a
+// first installation token, so that bad credentials fail early.
+func (t *Tester) newGithubAppAuth() (*githubAppAuth, error) {
+	if t.GithubAppPrivateKey == "" {
+		t.GithubAppPrivateKey = os.Getenv("GITHUB_APP_PRIVATE_KEY")
+	}
+	if t.GithubAppInstallationID == "" || t.GithubAppPrivateKey == "" {
+		return nil, fmt.Errorf("--github-app-id requires --github-app-installation-id and --github-app-private-key")
+	}
+	data, err := os.ReadFile(t.GithubAppPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %v", err)
+	}
+	key, err := parseRSAPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key %s: %v", t.GithubAppPrivateKey, err)
+	}
+
+	auth := &githubAppAuth{
+		apiEndpoint:    strings.TrimSuffix(t.GithubAPIEndpoint, "/"),
+		appID:          t.GithubAppID,
+		installationID: t.GithubAppInstallationID,
+		key:            key,
+	}
+	if _, err := auth.currentToken(); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+func (a *githubAppAuth) Name() string {
+	return "github-app"
+}
+
+func (a *githubAppAuth) String() string {
+	return fmt.Sprintf("%s - app %s installation %s", a.Name(), a.appID, a.installationID)
+}
+
+// SetAuth sets the basic auth GitHub expects installation tokens in.
+func (a *githubAppAuth) SetAuth(r *http.Request) {
+	token, err := a.currentToken()
+	if err != nil {
+		// the request then fails with an authentication error
+		klog.Warningf("failed to refresh GitHub App installation token: %v", err)
+	}
+	r.SetBasicAuth("x-access-token", token)
+}
+
+// currentToken returns the current installation token, creating a new one
+// when it is about to expire.
+func (a *githubAppAuth) currentToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > tokenRefreshMargin {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return a.token, err
+	}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", a.apiEndpoint, a.installationID)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return a.token, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return a.token, fmt.Errorf("failed to create GitHub App installation token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return a.token, fmt.Errorf("failed to create GitHub App installation token: POST %s: %s", url, resp.Status)
+	}
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return a.token, fmt.Errorf("failed to parse GitHub App installation token: %v", err)
+	}
+
+	klog.V(1).Infof("Created GitHub App installation token expiring at %s", body.ExpiresAt.Format(time.RFC3339))
+	a.token, a.expires = body.Token, body.ExpiresAt
+	return a.token, nil
+}
+
+// jwt returns the short-lived JSON web token that authenticates as the App.
+func (a *githubAppAuth) jwt() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		// allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded PKCS#1 or PKCS#8 RSA private key,
+// as downloaded from the GitHub App settings.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA private key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,150 @@
+package tester
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// goVersionAuto is the --go-version installing the toolchain required by
+// the go.mod of the cloned repo.
+const goVersionAuto = "auto"
+
+// goModGoVersions returns the go and toolchain directives of the go.mod
+// file at path, without their go prefix.
+func goModGoVersions(path string) (goVersion, toolchain string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			goVersion = fields[1]
+		case "toolchain":
+			toolchain = strings.TrimPrefix(fields[1], "go")
+		}
+	}
+	return goVersion, toolchain, scanner.Err()
+}
+
+// resolveGoVersion sets the auto --go-version to the toolchain, or else the
+// go version, required by the go.mod of the cloned repo.
+func (t *Tester) resolveGoVersion() error {
+	path := filepath.Join(t.sourceDir(), "go.mod")
+	goVersion, toolchain, err := goModGoVersions(path)
+	if err != nil {
+		return fmt.Errorf("--go-version=%s requires a go.mod in the cloned repo: %v", goVersionAuto, err)
+	}
+	version := toolchain
+	if version == "" {
+		version = goVersion
+	}
+	if version == "" {
+		return fmt.Errorf("--go-version=%s: no go version in %s", goVersionAuto, path)
+	}
+	version = goReleaseVersion(version)
+	klog.V(0).Infof("Using Go %s required by %s", version, path)
+	t.GoVersion = version
+	return nil
+}
+
+// goReleaseVersion returns the version of the first release of the Go
+// language version, e.g. 1.22.0 for 1.22, as since Go 1.21 the first
+// release of a language version is x.y.0 rather than x.y.
+func goReleaseVersion(version string) string {
+	if strings.Count(version, ".") != 1 || strings.IndexFunc(version, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	}) >= 0 {
+		return version
+	}
+	if compareGoVersions(version, "1.21") < 0 {
+		return version
+	}
+	return version + ".0"
+}
+
+// checkGoMod fails when the go.mod of the cloned repo requires a newer Go
+// than the toolchain that would build it, instead of leaving it to a wall
+// of compiler errors.
+func (t *Tester) checkGoMod() error {
+	if !t.buildsWithGo() {
+		return nil
+	}
+	path := filepath.Join(t.sourceDir(), "go.mod")
+	required, _, err := goModGoVersions(path)
+	if err != nil || required == "" {
+		klog.V(2).Infof("not checking the Go version of the cloned repo: %v", err)
+		return nil
+	}
+
+	cmd := exec.CommandContext(t.context(), "go", "env", "GOVERSION", "GOTOOLCHAIN")
+	cmd.SetEnv(t.env...)
+	lines, err := exec.OutputLines(cmd)
+	if err != nil || len(lines) != 2 {
+		klog.Warningf("failed to get the Go version, not checking it against %s: %v", path, err)
+		return nil
+	}
+	current, toolchain := strings.TrimPrefix(lines[0], "go"), lines[1]
+	if compareGoVersions(current, required) >= 0 {
+		return nil
+	}
+	// go 1.21 and later switch to the required toolchain themselves
+	if toolchain != "local" && compareGoVersions(current, "1.21") >= 0 {
+		klog.V(1).Infof("%s requires go %s, go %s will switch to it", path, required, current)
+		return nil
+	}
+	return fmt.Errorf("%s requires go %s but the build uses go %s, set --go-version=%s or --go-version=%s", path, required, current, required, goVersionAuto)
+}
+
+// buildsWithGo reports whether the tests are built with the Go toolchain of
+// the tester rather than downloaded, or built by bazel or the --test-cmd.
+func (t *Tester) buildsWithGo() bool {
+	switch t.RunMode {
+	case runModeGoTest:
+		return true
+	case runModeGinkgo:
+		return t.TestPackageVersion == "" && t.BuildCmd != "" && t.buildSystem() != buildSystemBazel
+	}
+	return false
+}
+
+// compareGoVersions compares the Go versions a and b, e.g. 1.21 and
+// 1.21.3, returning -1, 0 or 1. Pre-releases compare as their release.
+func compareGoVersions(a, b string) int {
+	pa, pb := goVersionParts(a), goVersionParts(b)
+	for i := range pa {
+		switch {
+		case pa[i] < pb[i]:
+			return -1
+		case pa[i] > pb[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+func goVersionParts(version string) [3]int {
+	var parts [3]int
+	for i, s := range strings.SplitN(version, ".", 3) {
+		// drop pre-release suffixes, e.g. 1.22rc1
+		if end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			s = s[:end]
+		}
+		parts[i], _ = strconv.Atoi(s)
+	}
+	return parts
+}
@@ -0,0 +1,98 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareGoVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.21", b: "1.21", want: 0},
+		{a: "1.21", b: "1.21.0", want: 0},
+		{a: "1.21.3", b: "1.21", want: 1},
+		{a: "1.21", b: "1.21.3", want: -1},
+		{a: "1.9", b: "1.10", want: -1},
+		{a: "1.22.1", b: "1.21.10", want: 1},
+		{a: "2.0", b: "1.99", want: 1},
+		{a: "1.22rc1", b: "1.22", want: 0},
+		{a: "1.22rc1", b: "1.21.5", want: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			if got := compareGoVersions(tc.a, tc.b); got != tc.want {
+				t.Errorf("compareGoVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGoModGoVersions(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		wantGo        string
+		wantToolchain string
+	}{
+		{
+			name:   "go directive",
+			data:   "module example.com/m\n\ngo 1.20\n\nrequire example.com/dep v1.0.0\n",
+			wantGo: "1.20",
+		},
+		{
+			name:          "toolchain directive",
+			data:          "module example.com/m\n\ngo 1.21\n\ntoolchain go1.21.4\n",
+			wantGo:        "1.21",
+			wantToolchain: "1.21.4",
+		},
+		{
+			name: "no directive",
+			data: "module example.com/m\n",
+		},
+		{
+			name:   "comments and requires",
+			data:   "// comment\nmodule example.com/m\n\ngo 1.19\n\nrequire (\n\texample.com/dep v1.0.0\n)\n",
+			wantGo: "1.19",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "go.mod")
+			if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			goVersion, toolchain, err := goModGoVersions(path)
+			if err != nil {
+				t.Fatalf("goModGoVersions() failed: %v", err)
+			}
+			if goVersion != tc.wantGo || toolchain != tc.wantToolchain {
+				t.Errorf("goModGoVersions() = %q, %q, want %q, %q", goVersion, toolchain, tc.wantGo, tc.wantToolchain)
+			}
+		})
+	}
+}
+
+func TestGoReleaseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "1.20", want: "1.20"},
+		{version: "1.21", want: "1.21.0"},
+		{version: "1.22", want: "1.22.0"},
+		{version: "1.22.3", want: "1.22.3"},
+		{version: "1.22rc1", want: "1.22rc1"},
+		{version: "2.0", want: "2.0.0"},
+		{version: "1.9", want: "1.9"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.version, func(t *testing.T) {
+			if got := goReleaseVersion(tc.version); got != tc.want {
+				t.Errorf("goReleaseVersion(%q) = %q, want %q", tc.version, got, tc.want)
+			}
+		})
+	}
+}
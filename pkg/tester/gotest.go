@@ -0,0 +1,61 @@
+package tester
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+)
+
+// runGoTest runs the suite with go test inside the cloned repo, for repos
+// whose suites are plain Go tests rather than prebuilt ginkgo binaries.
+// With --repeat or --until-it-fails the tester reruns go test itself, since
+// go test -count doesn't stop at the first failure.
+func (t *Tester) runGoTest() error {
+	tc, err := t.goTestCommand()
+	if err != nil {
+		return err
+	}
+	log, err := createTestLog()
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	for run := 1; ; run++ {
+		klog.V(0).Infof("Running go test (run %d) in %s as go %+v", run, tc.dir, t.redactAll(tc.args))
+		if err := t.runTestCmd(tc.command(t.env, log)); err != nil {
+			if run > 1 {
+				klog.V(0).Infof("go test failed on run %d", run)
+			}
+			return err
+		}
+		if !t.UntilItFails && run > t.Repeat {
+			return nil
+		}
+	}
+}
+
+// goTestCommand returns the go test invocation that runs the tests.
+func (t *Tester) goTestCommand() (*testCommand, error) {
+	extraTestArgs, err := shellquote.Split(t.TestArgs)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing --test-args: %v", err)
+	}
+
+	args := []string{
+		"test",
+		"-count=" + strconv.Itoa(t.GoTestCount),
+		"-timeout=" + t.Timeout.String(),
+	}
+	if t.GoTestRun != "" {
+		args = append(args, "-run="+t.GoTestRun)
+	}
+	args = append(args, t.GoTestPkgs...)
+	args = append(args, "-args", "--kubeconfig="+t.kubeconfigPath)
+	args = append(args, t.providerArgs()...)
+	args = append(args, t.namespaceArgs()...)
+	args = append(args, extraTestArgs...)
+	return &testCommand{path: "go", args: args, dir: t.sourceDir()}, nil
+}
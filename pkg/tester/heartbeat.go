@@ -0,0 +1,43 @@
+package tester
+
+import (
+	"time"
+
+	"k8s.io/klog"
+)
+
+// heartbeat logs that the tests are still running every
+// --heartbeat-interval until the returned func is called, so that CI
+// systems don't kill quiet suites for inactivity.
+func (t *Tester) heartbeat() (stop func()) {
+	if t.HeartbeatInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		start := time.Now()
+		ticker := time.NewTicker(t.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				phase, phaseStarted := t.currentPhase()
+				if phase == "" {
+					klog.V(0).Infof("Tests still running after %v", now.Sub(start).Round(time.Second))
+					continue
+				}
+				klog.V(0).Infof("Tests still running after %v, in the %s phase for %v",
+					now.Sub(start).Round(time.Second), phase, now.Sub(phaseStarted).Round(time.Second))
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
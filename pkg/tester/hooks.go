@@ -0,0 +1,24 @@
+package tester
+
+import (
+	"fmt"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// runHooks runs each of cmds with sh in the checkout dir with the test env,
+// stopping at the first failure.
+func (t *Tester) runHooks(phase string, cmds []string) error {
+	for _, raw := range cmds {
+		klog.V(0).Infof("Running %s hook %q", phase, t.redact(raw))
+		cmd := exec.CommandContext(t.context(), "sh", "-c", raw)
+		cmd.SetDir(t.sourceDir())
+		cmd.SetEnv(t.env...)
+		exec.InheritOutput(cmd)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %v", phase, t.redact(raw), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,472 @@
+package tester
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+const (
+	// inClusterWorkDir is the dir the test binaries are copied to in the
+	// test pod of --in-cluster.
+	inClusterWorkDir = "/work"
+	// inClusterJob is the name of the container of the job of --in-cluster,
+	// and the prefix of the names of the job and of its service account.
+	inClusterJob = "e2e"
+	// inClusterNamespacePrefix is the prefix of the default namespace of
+	// --in-cluster.
+	inClusterNamespacePrefix = "kubetest2-gitremote"
+	// inClusterManifest is the file, in the run dir, of the objects created
+	// for --in-cluster.
+	inClusterManifest = "in-cluster.json"
+)
+
+// runInCluster runs the ginkgo invocation tc in a job of the cluster under
+// test instead of on the runner, for clusters whose API is reachable but
+// whose network blocks the test traffic from outside. The pod waits for the
+// test binaries to be copied in, then runs them with the in-cluster config
+// of a cluster-admin service account. Its logs are streamed to the output
+// and log, and its artifacts copied back to the artifacts dir.
+func (t *Tester) runInCluster(tc *testCommand, log io.Writer) error {
+	if err := t.applyInClusterJob(tc); err != nil {
+		return err
+	}
+	defer t.deleteInClusterJob()
+
+	pod, err := t.waitForInClusterPod()
+	if err != nil {
+		return err
+	}
+	if err := t.copyToPod(pod); err != nil {
+		return err
+	}
+	if _, err := t.podExec(pod, nil, nil, "touch", path.Join(inClusterWorkDir, "ready")); err != nil {
+		return err
+	}
+
+	klog.V(0).Infof("Running ginkgo test in pod %s/%s", t.inClusterNamespace(), pod)
+	stopHeartbeat := t.heartbeat()
+	testErr := t.streamInClusterTest(pod, log)
+	stopHeartbeat()
+
+	if err := t.copyFromPod(pod); err != nil {
+		klog.Warningf("failed to collect the artifacts of pod %s: %v", pod, err)
+	}
+	if _, err := t.podExec(pod, nil, nil, "touch", path.Join(inClusterWorkDir, "collected")); err != nil {
+		klog.V(1).Infof("failed to release pod %s: %v", pod, err)
+	}
+	return testErr
+}
+
+// inClusterName returns prefix-<run id> as a DNS label, so that the objects
+// of concurrent runs don't collide.
+func (t *Tester) inClusterName(prefix string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(prefix+"-"+t.runID))
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+// inClusterNamespace returns the namespace of the job of --in-cluster.
+func (t *Tester) inClusterNamespace() string {
+	if t.InClusterNamespace != "" {
+		return t.InClusterNamespace
+	}
+	return t.inClusterName(inClusterNamespacePrefix)
+}
+
+// inClusterJobName returns the name of the job of --in-cluster and of its
+// service account.
+func (t *Tester) inClusterJobName() string {
+	return t.inClusterName(inClusterJob)
+}
+
+// inClusterArgs returns the args of tc with the paths of the runner replaced
+// by the ones in the test pod. The kubeconfig is dropped, for the e2e
+// framework to use the in-cluster config.
+func (t *Tester) inClusterArgs(tc *testCommand) []string {
+	var pairs []string
+	for local, remote := range map[string]string{
+		t.e2eTestPath:       "e2e.test",
+		t.kubectlPath:       "kubectl",
+		artifacts.BaseDir(): "artifacts",
+	} {
+		if local != "" {
+			pairs = append(pairs, local, path.Join(inClusterWorkDir, remote))
+		}
+	}
+	replacer := strings.NewReplacer(pairs...)
+	args := []string{path.Join(inClusterWorkDir, "ginkgo")}
+	for _, arg := range tc.args {
+		if strings.HasPrefix(arg, "--kubeconfig=") {
+			continue
+		}
+		args = append(args, replacer.Replace(arg))
+	}
+	return args
+}
+
+// applyInClusterJob creates the namespace, unless it exists, and the service
+// account, env secret and job of --in-cluster.
+func (t *Tester) applyInClusterJob(tc *testCommand) error {
+	work := func(name string) string { return path.Join(inClusterWorkDir, name) }
+	// the pod stays up after the tests until the artifacts are collected
+	script := strings.Join([]string{
+		"until [ -f " + work("ready") + " ]; do sleep 1; done",
+		"mkdir -p " + work("artifacts"),
+		"cd " + inClusterWorkDir,
+		shellquote.Join(t.inClusterArgs(tc)...) + "; echo $? > " + work("exit-code"),
+		"until [ -f " + work("collected") + " ]; do sleep 1; done",
+		"exit $(cat " + work("exit-code") + ")",
+	}, "\n")
+
+	env := map[string]string{}
+	for _, kv := range t.envOverrides {
+		key, value, _ := strings.Cut(kv, "=")
+		env[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(t.context(), t.PreflightTimeout)
+	defer cancel()
+	ns, job := t.inClusterNamespace(), t.inClusterJobName()
+	existing, err := t.kubectlOutput(ctx, "get", "namespace", ns, "--ignore-not-found", "--output=name")
+	if err != nil {
+		return fmt.Errorf("failed to get namespace %s: %v", ns, err)
+	}
+	meta := func(name string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "namespace": ns}
+	}
+	var objects []interface{}
+	// only a namespace created by this run is deleted afterwards
+	t.inClusterCreatedNamespace = strings.TrimSpace(string(existing)) == ""
+	if t.inClusterCreatedNamespace {
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": ns},
+		})
+	}
+	objects = append(objects,
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   meta(job),
+		},
+		map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "ClusterRoleBinding",
+			"metadata":   map[string]interface{}{"name": t.inClusterRoleBinding()},
+			"roleRef": map[string]interface{}{
+				"apiGroup": "rbac.authorization.k8s.io",
+				"kind":     "ClusterRole",
+				"name":     "cluster-admin",
+			},
+			"subjects": []interface{}{map[string]interface{}{
+				"kind":      "ServiceAccount",
+				"name":      job,
+				"namespace": ns,
+			}},
+		},
+		// the env may hold secrets, so it is not inlined in the job
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   meta(job + "-env"),
+			"stringData": env,
+		},
+		map[string]interface{}{
+			"apiVersion": "batch/v1",
+			"kind":       "Job",
+			"metadata":   meta(job),
+			"spec": map[string]interface{}{
+				"backoffLimit": 0,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"serviceAccountName": job,
+						"restartPolicy":      "Never",
+						"containers": []interface{}{map[string]interface{}{
+							"name":         inClusterJob,
+							"image":        t.InClusterImage,
+							"command":      []string{"sh", "-c", script},
+							"envFrom":      []interface{}{map[string]interface{}{"secretRef": map[string]string{"name": job + "-env"}}},
+							"volumeMounts": []interface{}{map[string]string{"name": "work", "mountPath": inClusterWorkDir}},
+						}},
+						"volumes": []interface{}{map[string]interface{}{
+							"name":     "work",
+							"emptyDir": map[string]interface{}{},
+						}},
+					},
+				},
+			},
+		},
+	)
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      objects,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	manifest := filepath.Join(t.runDir, inClusterManifest)
+	if err := os.WriteFile(manifest, data, 0600); err != nil {
+		return fmt.Errorf("failed to write in-cluster manifest: %v", err)
+	}
+
+	klog.V(0).Infof("Creating job %s/%s", ns, job)
+	if _, err := t.kubectlOutput(ctx, "apply", "--filename="+manifest); err != nil {
+		return fmt.Errorf("failed to create in-cluster job: %v", err)
+	}
+	return nil
+}
+
+// inClusterRoleBinding is the cluster-admin binding of the service account
+// of --in-cluster, which is cluster scoped and so named after the namespace.
+func (t *Tester) inClusterRoleBinding() string {
+	return t.inClusterNamespace() + "-" + t.inClusterJobName()
+}
+
+// deleteInClusterJob deletes the objects created by applyInClusterJob().
+func (t *Tester) deleteInClusterJob() {
+	ctx, cancel := context.WithTimeout(context.Background(), t.PreflightTimeout)
+	defer cancel()
+	ns, job := t.inClusterNamespace(), t.inClusterJobName()
+	objects := []string{"clusterrolebinding/" + t.inClusterRoleBinding()}
+	if t.inClusterCreatedNamespace {
+		klog.V(0).Infof("Deleting namespace %s", ns)
+		objects = append(objects, "namespace/"+ns)
+	} else {
+		klog.V(0).Infof("Deleting job %s/%s", ns, job)
+		objects = append(objects, "job/"+job, "serviceaccount/"+job, "secret/"+job+"-env")
+	}
+	if _, err := t.kubectlOutput(ctx, append([]string{"delete", "--ignore-not-found", "--wait=false",
+		"--namespace=" + ns}, objects...)...); err != nil {
+		klog.Warningf("failed to delete in-cluster job: %v", err)
+	}
+}
+
+// waitForInClusterPod returns the pod of the job once it is running.
+func (t *Tester) waitForInClusterPod() (string, error) {
+	ctx, cancel := context.WithTimeout(t.context(), t.NodeReadyTimeout)
+	defer cancel()
+
+	var pod string
+	err := poll(ctx, func() error {
+		out, err := t.kubectlOutput(ctx, "get", "pods", "--namespace="+t.inClusterNamespace(),
+			"--selector=job-name="+t.inClusterJobName(), "--output=jsonpath={.items[0].metadata.name} {.items[0].status.phase}")
+		if err != nil {
+			return err
+		}
+		name, phase, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+		if phase != "Running" {
+			return fmt.Errorf("pod %s is %s", name, phase)
+		}
+		pod = name
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("in-cluster test pod did not start: %v", err)
+	}
+	return pod, nil
+}
+
+// copyToPod copies the test binaries to the work dir of pod.
+func (t *Tester) copyToPod(pod string) error {
+	files := map[string]string{
+		"ginkgo":   t.ginkgoPath,
+		"e2e.test": t.e2eTestPath,
+	}
+	if _, err := os.Stat(t.kubectlPath); err == nil {
+		files["kubectl"] = t.kubectlPath
+	}
+
+	klog.V(0).Infof("Copying the test binaries to pod %s", pod)
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeTar(w, files))
+	}()
+	// unblock the writer when kubectl exits early
+	defer r.Close()
+	if _, err := t.podExec(pod, r, nil, "tar", "-xf", "-", "-C", inClusterWorkDir); err != nil {
+		return fmt.Errorf("failed to copy the test binaries to pod %s: %v", pod, err)
+	}
+	return nil
+}
+
+// copyFromPod copies the artifacts written by the tests in pod to the
+// artifacts dir.
+func (t *Tester) copyFromPod(pod string) error {
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := t.podExec(pod, nil, w, "tar", "-cf", "-", "-C", path.Join(inClusterWorkDir, "artifacts"), ".")
+		w.CloseWithError(err)
+		done <- err
+	}()
+	err := extractTar(r, artifacts.BaseDir())
+	r.Close()
+	if execErr := <-done; execErr != nil {
+		return execErr
+	}
+	return err
+}
+
+// streamInClusterTest streams the logs of pod to the output and log until
+// the tests in it exit, and returns their error.
+func (t *Tester) streamInClusterTest(pod string, log io.Writer) error {
+	ctx, cancel := context.WithCancel(t.context())
+	logs := exec.CommandContext(ctx, t.kubectl(), "--kubeconfig="+t.kubeconfigPath, "logs", "--follow",
+		"--namespace="+t.inClusterNamespace(), pod)
+	exec.SetOutput(logs, io.MultiWriter(os.Stdout, log), os.Stderr)
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		if err := logs.Run(); err != nil && ctx.Err() == nil {
+			klog.Warningf("streaming the logs of pod %s stopped: %v", pod, err)
+		}
+	}()
+	defer func() {
+		// the pod waits for its artifacts to be collected, so the logs
+		// never end on their own
+		time.Sleep(pollInterval)
+		cancel()
+		<-logsDone
+	}()
+
+	deadline := time.After(t.testDeadline())
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-deadline:
+			return fmt.Errorf("%w: %v", errTesterTimeout, t.testDeadline())
+		case <-t.context().Done():
+			return fmt.Errorf("in-cluster test canceled: %w", t.context().Err())
+		case <-ticker.C:
+		}
+		out, err := t.podExec(pod, nil, nil, "cat", path.Join(inClusterWorkDir, "exit-code"))
+		if err != nil {
+			if phase := t.podPhase(pod); phase != "" && phase != "Running" {
+				return fmt.Errorf("in-cluster test pod %s is %s", pod, phase)
+			}
+			continue
+		}
+		code := strings.TrimSpace(string(out))
+		if code == "0" {
+			return nil
+		}
+		return fmt.Errorf("in-cluster test pod %s exited with %s", pod, code)
+	}
+}
+
+// podPhase returns the phase of pod, empty if it failed to be read.
+func (t *Tester) podPhase(pod string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), t.PreflightTimeout)
+	defer cancel()
+	out, err := t.kubectlOutput(ctx, "get", "pod", pod, "--namespace="+t.inClusterNamespace(),
+		"--output=jsonpath={.status.phase}")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// podExec runs args in pod with stdin and stdout, and returns the output
+// when stdout is nil.
+func (t *Tester) podExec(pod string, stdin io.Reader, stdout io.Writer, args ...string) ([]byte, error) {
+	kubectlArgs := []string{"--kubeconfig=" + t.kubeconfigPath, "exec", "--namespace=" + t.inClusterNamespace()}
+	if stdin != nil {
+		kubectlArgs = append(kubectlArgs, "--stdin")
+	}
+	kubectlArgs = append(kubectlArgs, pod, "--")
+	cmd := exec.CommandContext(t.context(), t.kubectl(), append(kubectlArgs, args...)...)
+	if stdin != nil {
+		cmd.SetStdin(stdin)
+	}
+	var out, stderr strings.Builder
+	if stdout == nil {
+		stdout = &out
+	}
+	exec.SetOutput(cmd, stdout, &stderr)
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return []byte(out.String()), nil
+}
+
+// writeTar writes the files, by name in the archive, as a tar to w.
+func writeTar(w io.Writer, files map[string]string) error {
+	tw := tar.NewWriter(w)
+	for name, src := range files {
+		f, err := os.Open(src)
+		if err != nil {
+			return err
+		}
+		info, err := f.Stat()
+		if err == nil {
+			err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		if err == nil {
+			_, err = io.Copy(tw, f)
+		}
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %v", src, err)
+		}
+	}
+	return tw.Close()
+}
+
+// extractTar extracts the dirs and regular files of the tar read from r to
+// dir.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error during tar read: %v", err)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if rel, err := filepath.Rel(dir, dest); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("invalid path %s in tar", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+				return err
+			}
+			if err := extractFile(tr, dest); err != nil {
+				return err
+			}
+		}
+	}
+}
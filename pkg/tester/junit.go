@@ -0,0 +1,209 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// junitTestSuites is the subset of the junit format needed to validate reports.
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string  `xml:"name,attr"`
+	Tests    int     `xml:"tests,attr"`
+	Failures int     `xml:"failures,attr"`
+	Errors   int     `xml:"errors,attr"`
+	Skipped  int     `xml:"skipped,attr"`
+	Time     float64 `xml:"time,attr"`
+
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Skipped   *junitMessage `xml:"skipped"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitSummary aggregates the results of all junit reports of a run.
+type junitSummary struct {
+	Tests    int
+	Failures int
+	Skipped  int
+	Duration time.Duration
+}
+
+// junitReports returns the paths of the junit reports in the artifacts dir.
+func junitReports() ([]string, error) {
+	return filepath.Glob(filepath.Join(artifacts.BaseDir(), "junit_*.xml"))
+}
+
+// parseJUnit parses a junit report whose root is either <testsuites> or a
+// single <testsuite>.
+func parseJUnit(path string) (*junitTestSuites, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suites := &junitTestSuites{}
+	if err := xml.Unmarshal(data, suites); err != nil {
+		return nil, fmt.Errorf("failed to parse junit report %s: %v", path, err)
+	}
+	if len(suites.Suites) == 0 {
+		suite := junitTestSuite{}
+		if err := xml.Unmarshal(data, &suite); err != nil {
+			return nil, fmt.Errorf("failed to parse junit report %s: %v", path, err)
+		}
+		suites.Suites = []junitTestSuite{suite}
+	}
+	return suites, nil
+}
+
+// suiteNodes are the ginkgo v2 leaf node types reported in junit that are
+// not specs, and so cannot be focused on.
+var suiteNodes = []string{
+	"[BeforeSuite]", "[AfterSuite]",
+	"[SynchronizedBeforeSuite]", "[SynchronizedAfterSuite]",
+	"[ReportBeforeSuite]", "[ReportAfterSuite]",
+	"[DeferCleanup (Suite)]",
+}
+
+// specLabels matches the labels ginkgo v2 appends to junit test case names.
+var specLabels = regexp.MustCompile(` \[[^\]]*\]$`)
+
+// failedSpecs returns the names of the specs that failed in the junit report
+// at path, as matched by the ginkgo focus, without duplicates.
+func failedSpecs(path string) ([]string, error) {
+	suites, err := parseJUnit(path)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	names := []string{}
+	for _, suite := range suites.Suites {
+		for _, tc := range suite.TestCases {
+			if tc.Failure == nil && tc.Error == nil {
+				continue
+			}
+			name := tc.Name
+			suiteNode := false
+			for _, node := range suiteNodes {
+				suiteNode = suiteNode || strings.HasPrefix(name, node)
+			}
+			if suiteNode {
+				klog.Warningf("not rerunning %q, it is not a spec", name)
+				continue
+			}
+			// the focus only matches the spec text, so drop what ginkgo v2 adds;
+			// dropping text that only looks like labels still matches the spec
+			name = strings.TrimPrefix(name, "[It] ")
+			name = specLabels.ReplaceAllString(name, "")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// validateJUnitReports checks that at least one well formed junit report
+// was written to the artifacts dir.
+func validateJUnitReports() error {
+	reports, err := junitReports()
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no junit reports found in %s", artifacts.BaseDir())
+	}
+	for _, report := range reports {
+		if _, err := parseJUnit(report); err != nil {
+			return err
+		}
+		klog.V(1).Infof("Found junit report %s", report)
+	}
+	return nil
+}
+
+// summarizeJUnitReports aggregates the results of the junit reports in the
+// artifacts dir.
+func summarizeJUnitReports() (*junitSummary, error) {
+	reports, err := junitReports()
+	if err != nil {
+		return nil, err
+	}
+	return summarizeJUnit(reports)
+}
+
+// summarizeJUnit aggregates the results of the junit reports, counting each
+// test case once.
+func summarizeJUnit(reports []string) (*junitSummary, error) {
+	// with ginkgo v2 the same specs may be reported both by the e2e binary
+	// and by the ginkgo CLI
+	seen := map[string]bool{}
+	summary := &junitSummary{}
+	for _, report := range reports {
+		suites, err := parseJUnit(report)
+		if err != nil {
+			return nil, err
+		}
+		for _, suite := range suites.Suites {
+			added := false
+			for _, tc := range suite.TestCases {
+				if seen[tc.Name] {
+					continue
+				}
+				seen[tc.Name] = true
+				added = true
+				summary.Tests++
+				switch {
+				case tc.Failure != nil || tc.Error != nil:
+					summary.Failures++
+				case tc.Skipped != nil:
+					summary.Skipped++
+				}
+			}
+			// a suite that only repeats reported test cases took no extra time
+			if added {
+				summary.Duration += time.Duration(suite.Time * float64(time.Second))
+			}
+		}
+	}
+	return summary, nil
+}
+
+// writeResultMetadata records the test results in the kubetest2 metadata.
+func (t *Tester) writeResultMetadata() error {
+	meta := map[string]string{}
+	if t.JUnit {
+		summary, err := summarizeJUnitReports()
+		if err != nil {
+			return err
+		}
+		meta["tests-total"] = strconv.Itoa(summary.Tests)
+		meta["tests-passed"] = strconv.Itoa(summary.Tests - summary.Failures - summary.Skipped)
+		meta["tests-failed"] = strconv.Itoa(summary.Failures)
+		meta["tests-skipped"] = strconv.Itoa(summary.Skipped)
+		meta["tests-duration"] = summary.Duration.String()
+	}
+	return addMetadata(meta)
+}
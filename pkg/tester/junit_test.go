@@ -0,0 +1,78 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarizeJUnit(t *testing.T) {
+	const e2eReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1">
+  <testsuite name="Kubernetes e2e suite" tests="3" failures="1" errors="0" skipped="1" time="10">
+    <testcase name="[It] passes" time="4"></testcase>
+    <testcase name="[It] fails" time="6"><failure message="boom"></failure></testcase>
+    <testcase name="[It] is skipped" time="0"><skipped message="skipped"></skipped></testcase>
+  </testsuite>
+</testsuites>`
+	const otherReport = `<testsuite name="Kubernetes e2e suite" tests="1" failures="0" errors="1" skipped="0" time="5">
+  <testcase name="[It] errors" time="5"><error message="boom"></error></testcase>
+</testsuite>`
+
+	tests := []struct {
+		name    string
+		reports []string
+		want    junitSummary
+	}{
+		{
+			name:    "single report",
+			reports: []string{e2eReport},
+			want:    junitSummary{Tests: 3, Failures: 1, Skipped: 1, Duration: 10 * time.Second},
+		},
+		{
+			name:    "report repeated by the ginkgo CLI",
+			reports: []string{e2eReport, e2eReport},
+			want:    junitSummary{Tests: 3, Failures: 1, Skipped: 1, Duration: 10 * time.Second},
+		},
+		{
+			name:    "testsuite root and errors",
+			reports: []string{e2eReport, otherReport},
+			want:    junitSummary{Tests: 4, Failures: 2, Skipped: 1, Duration: 15 * time.Second},
+		},
+		{
+			name: "no reports",
+			want: junitSummary{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := []string{}
+			for i, report := range tc.reports {
+				path := filepath.Join(dir, "junit_"+string(rune('a'+i))+".xml")
+				if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path)
+			}
+			got, err := summarizeJUnit(paths)
+			if err != nil {
+				t.Fatalf("summarizeJUnit() failed: %v", err)
+			}
+			if *got != tc.want {
+				t.Errorf("summarizeJUnit() = %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeJUnitInvalidReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit_01.xml")
+	if err := os.WriteFile(path, []byte("not xml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := summarizeJUnit([]string{path}); err == nil {
+		t.Error("summarizeJUnit() succeeded on an invalid report")
+	}
+}
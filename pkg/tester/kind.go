@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// kindKubeconfig is the kubeconfig file, in the run dir, of the kind cluster
+// created for --create-kind-cluster.
+const kindKubeconfig = "kind-kubeconfig"
+
+// createKindCluster creates the kind cluster of --create-kind-cluster and
+// sets its kubeconfig as the one to test against. The cluster is deleted by
+// deleteKindCluster().
+func (t *Tester) createKindCluster() error {
+	if _, err := osexec.LookPath("kind"); err != nil {
+		return fmt.Errorf("--create-kind-cluster requires kind: %v", err)
+	}
+	path := filepath.Join(t.runDir, kindKubeconfig)
+	if err := os.MkdirAll(t.runDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	args := []string{"create", "cluster",
+		"--name", t.KindClusterName,
+		"--kubeconfig", path,
+		"--wait", t.NodeReadyTimeout.String(),
+	}
+	if t.KindNodeImage != "" {
+		args = append(args, "--image", t.KindNodeImage)
+	}
+	if t.KindConfig != "" {
+		args = append(args, "--config", t.KindConfig)
+	}
+
+	klog.V(0).Infof("No kubeconfig available, creating kind cluster %s", t.KindClusterName)
+	// a failed create may leave the node containers behind
+	t.kindCluster = t.KindClusterName
+	cmd := exec.CommandContext(t.context(), "kind", args...)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create kind cluster %s: %v", t.KindClusterName, err)
+	}
+	t.Kubeconfig = stringArray{path}
+	return nil
+}
+
+// deleteKindCluster deletes the cluster created by createKindCluster(), if
+// any.
+func (t *Tester) deleteKindCluster() {
+	if t.kindCluster == "" {
+		return
+	}
+	klog.V(0).Infof("Deleting kind cluster %s", t.kindCluster)
+	cmd := exec.Command("kind", "delete", "cluster",
+		"--name", t.kindCluster,
+		"--kubeconfig", filepath.Join(t.runDir, kindKubeconfig),
+	)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		klog.Warningf("failed to delete kind cluster %s: %v", t.kindCluster, err)
+		return
+	}
+	t.kindCluster = ""
+}
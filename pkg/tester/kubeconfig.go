@@ -0,0 +1,184 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// runDirKubeconfigs are the kubeconfig files that kubetest2 deployers
+// generate in the run dir.
+var runDirKubeconfigs = []string{"kubetest2-kubeconfig", "kubeconfig"}
+
+// mergedKubeconfig is the kubeconfig file, in the run dir, that several
+// kubeconfigs or a --context are merged into.
+const mergedKubeconfig = "merged-kubeconfig"
+
+// generatedKubeconfig is the kubeconfig file, in the run dir, generated for
+// --server.
+const generatedKubeconfig = "generated-kubeconfig"
+
+// resolveKubeconfig sets kubeconfigPath from, in order of precedence, the
+// kubeconfig generated for --server, the --kubeconfig flag, the KUBECONFIG
+// env var and the run dir. When several
+// kubeconfigs or a --context are given, they are left to mergeKubeconfigs()
+// and kubeconfigPath is the first kubeconfig until then.
+func (t *Tester) resolveKubeconfig() error {
+	configs := append([]string(nil), t.Kubeconfig...)
+	if t.Server != "" {
+		path := filepath.Join(t.runDir, generatedKubeconfig)
+		// a dry run must not write credentials
+		if !t.DryRun {
+			if err := t.generateKubeconfig(path); err != nil {
+				return err
+			}
+		}
+		configs = []string{path}
+	}
+	if len(configs) == 0 {
+		configs = filepath.SplitList(os.Getenv("KUBECONFIG"))
+	}
+	if len(configs) == 0 {
+		for _, name := range runDirKubeconfigs {
+			path := filepath.Join(t.runDir, name)
+			if _, err := os.Stat(path); err == nil {
+				configs = []string{path}
+				break
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("kubeconfig path not provided")
+	}
+
+	// ginkgo changes its working directory while executing, so relative
+	// paths would not resolve.
+	for i, config := range configs {
+		if !filepath.IsAbs(config) {
+			abs, err := filepath.Abs(config)
+			if err != nil {
+				return fmt.Errorf("failed to convert kubeconfig to absolute path: %v", err)
+			}
+			configs[i] = abs
+		}
+	}
+
+	t.kubeconfigPath = configs[0]
+	if len(configs) > 1 || t.Context != "" || t.SocksProxy != "" || t.SSHBastion != "" {
+		t.kubeconfigs = configs
+		klog.V(0).Infof("Using kubeconfigs %s", strings.Join(configs, ", "))
+		return nil
+	}
+	klog.V(0).Infof("Using kubeconfig at %s", t.kubeconfigPath)
+	return nil
+}
+
+// generateKubeconfig writes a kubeconfig for the --server to path. It is
+// written as JSON, which kubeconfig loaders accept as well.
+func (t *Tester) generateKubeconfig(path string) error {
+	if t.Token == "" {
+		t.Token = os.Getenv("KUBE_TOKEN")
+	}
+
+	cluster := map[string]interface{}{"server": t.Server}
+	if t.CACert != "" {
+		ca, err := filepath.Abs(t.CACert)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(ca); err != nil {
+			return fmt.Errorf("invalid --ca-cert: %v", err)
+		}
+		cluster["certificate-authority"] = ca
+	}
+	user := map[string]interface{}{}
+	switch {
+	case t.ExecCredential != "":
+		args, err := shellquote.Split(t.ExecCredential)
+		if err != nil || len(args) == 0 {
+			return fmt.Errorf("invalid --exec-credential %q: %v", t.ExecCredential, err)
+		}
+		user["exec"] = map[string]interface{}{
+			"apiVersion":      "client.authentication.k8s.io/v1beta1",
+			"command":         args[0],
+			"args":            args[1:],
+			"interactiveMode": "Never",
+		}
+	case t.Token != "":
+		user["token"] = t.Token
+	}
+
+	const name = "kubetest2"
+	config := map[string]interface{}{
+		"apiVersion":      "v1",
+		"kind":            "Config",
+		"clusters":        []interface{}{map[string]interface{}{"name": name, "cluster": cluster}},
+		"users":           []interface{}{map[string]interface{}{"name": name, "user": user}},
+		"contexts":        []interface{}{map[string]interface{}{"name": name, "context": map[string]string{"cluster": name, "user": name}}},
+		"current-context": name,
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	klog.V(1).Infof("Generating kubeconfig for %s at %s", t.Server, path)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// mergeKubeconfigs flattens the kubeconfigs into a single file with kubectl,
+// as the e2e tests only take one kubeconfig, switches it to the --context
+// and points it to the SOCKS5 proxy of --socks-proxy or --ssh-bastion. The
+// merged kubeconfig is also exported as $KUBECONFIG to the test processes.
+// It is a no-op when there is nothing, or nothing left, to merge.
+func (t *Tester) mergeKubeconfigs() error {
+	if len(t.kubeconfigs) == 0 {
+		return nil
+	}
+	path := filepath.Join(t.runDir, mergedKubeconfig)
+
+	cmd := exec.CommandContext(t.context(), t.kubectl(), "config", "view", "--flatten", "--raw")
+	cmd.SetEnv(append(os.Environ(), "KUBECONFIG="+strings.Join(t.kubeconfigs, string(filepath.ListSeparator)))...)
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to merge kubeconfigs %s: %v", strings.Join(t.kubeconfigs, ", "), err)
+	}
+	if err := os.WriteFile(path, out, 0600); err != nil {
+		return err
+	}
+
+	if t.Context != "" {
+		cmd := exec.CommandContext(t.context(), t.kubectl(), "--kubeconfig="+path, "config", "use-context", t.Context)
+		exec.NoOutput(cmd)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to use kubeconfig context %q: %v", t.Context, err)
+		}
+	}
+
+	proxyURL := t.SocksProxy
+	if t.SSHBastion != "" {
+		if err := t.startTunnel(); err != nil {
+			return err
+		}
+		proxyURL = t.tunnelURL
+	}
+	if proxyURL != "" {
+		if err := setProxyURL(t.kubectl(), path, proxyURL); err != nil {
+			return err
+		}
+	}
+
+	klog.V(0).Infof("Using kubeconfig at %s, merged from %s", path, strings.Join(t.kubeconfigs, ", "))
+	t.kubeconfigPath = path
+	t.kubeconfigs = nil
+	t.env = mergeEnv(t.env, []string{"KUBECONFIG=" + path})
+	return nil
+}
@@ -0,0 +1,89 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	osexec "os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// kubectlReleaseURL is where kubectl release binaries and version markers are published.
+const kubectlReleaseURL = "https://dl.k8s.io/release"
+
+var releaseVersionRe = regexp.MustCompile(`^v\d+\.\d+\.\d+`)
+
+// acquireKubectl makes sure kubectlPath points to a kubectl binary. When
+// none was acquired with the test package and there is none on PATH, the
+// latest stable kubectl is downloaded to query the server version, and then
+// replaced with the kubectl release matching the cluster.
+func (t *Tester) acquireKubectl() error {
+	if t.kubectlPath != "" {
+		return nil
+	}
+	if path, err := osexec.LookPath("kubectl"); err == nil {
+		klog.V(1).Infof("Using kubectl from PATH at %s", path)
+		t.kubectlPath = path
+		return nil
+	}
+
+	if t.Offline {
+		return offlineError("kubectl", "a kubectl on PATH, or --acquire-kubectl=false")
+	}
+	path := filepath.Join(t.runDir, "kubectl")
+	stable, err := fetchString(t.context(), kubectlReleaseURL+"/stable.txt")
+	if err != nil {
+		return fmt.Errorf("failed to get the latest stable kubectl version: %v", err)
+	}
+	if err := downloadKubectl(t.context(), stable, path); err != nil {
+		return err
+	}
+	t.kubectlPath = path
+
+	serverVersion, err := t.serverVersion()
+	if err != nil {
+		klog.Warningf("failed to get the cluster version, using kubectl %s: %v", stable, err)
+		return nil
+	}
+	if serverVersion != stable {
+		klog.V(0).Infof("Downloading kubectl %s to match the cluster version", serverVersion)
+		return downloadKubectl(t.context(), serverVersion, path)
+	}
+	return nil
+}
+
+// serverVersion returns the release version (e.g. v1.28.2) of the cluster.
+func (t *Tester) serverVersion() (string, error) {
+	cmd := exec.CommandContext(t.context(), t.kubectl(), "--kubeconfig="+t.kubeconfigPath, "version", "--output=json")
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return "", err
+	}
+	var version struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal(out, &version); err != nil {
+		return "", fmt.Errorf("failed to parse kubectl version: %v", err)
+	}
+	// strip provider suffixes, e.g. v1.28.2-gke.1157000
+	release := releaseVersionRe.FindString(version.ServerVersion.GitVersion)
+	if release == "" {
+		return "", fmt.Errorf("unexpected server version %q", version.ServerVersion.GitVersion)
+	}
+	return release, nil
+}
+
+func downloadKubectl(ctx context.Context, version, dest string) error {
+	url := fmt.Sprintf("%s/%s/bin/%s/%s/kubectl", kubectlReleaseURL, version, runtime.GOOS, runtime.GOARCH)
+	if err := downloadFile(ctx, url, dest, 0755); err != nil {
+		return fmt.Errorf("failed to download kubectl %s: %v", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// klogHeader matches the header klog prefixes each entry with, e.g.
+// "I1014 16:23:04.413951   30405 git.go:23] ".
+var klogHeader = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\s+\d+ ([^\]]+)\] `)
+
+var klogLevels = map[string]string{"I": "info", "W": "warning", "E": "error", "F": "fatal"}
+
+// configureLogging switches klog to JSON lines on stderr for
+// --log-format=json, and adds the run id to the text entries otherwise.
+func (t *Tester) configureLogging() error {
+	var w io.Writer
+	switch t.LogFormat {
+	case logFormatText:
+		if t.runID == "" {
+			return nil
+		}
+		w = &textLogWriter{out: os.Stderr, runID: t.runID}
+	case logFormatJSON:
+		w = &jsonLogWriter{t: t, out: os.Stderr, start: time.Now(), runID: t.runID}
+	default:
+		return fmt.Errorf("unsupported --log-format %q, must be %q or %q", t.LogFormat, logFormatText, logFormatJSON)
+	}
+
+	// route every entry once to the info output, and nothing to stderr
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	for name, value := range map[string]string{"logtostderr": "false", "alsologtostderr": "false", "stderrthreshold": "FATAL"} {
+		if err := fs.Set(name, value); err != nil {
+			return err
+		}
+	}
+	klog.SetOutputBySeverity("INFO", w)
+	for _, severity := range []string{"WARNING", "ERROR", "FATAL"} {
+		klog.SetOutputBySeverity(severity, io.Discard)
+	}
+	return nil
+}
+
+// textLogWriter adds the run id after the header of klog entries, e.g.
+// "I1014 16:23:04.413951   30405 git.go:23] [run 1234] Cloning ...".
+type textLogWriter struct {
+	out   io.Writer
+	runID string
+
+	mu sync.Mutex
+}
+
+// Write is called by klog with one whole entry at a time.
+func (w *textLogWriter) Write(p []byte) (int, error) {
+	entry := p
+	if loc := klogHeader.FindIndex(p); loc != nil {
+		entry = make([]byte, 0, len(p)+len(w.runID)+7)
+		entry = append(entry, p[:loc[1]]...)
+		entry = append(entry, "[run "+w.runID+"] "...)
+		entry = append(entry, p[loc[1]:]...)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(entry); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// jsonLogWriter rewrites klog entries as JSON lines with the run id and the
+// current phase, see timed().
+type jsonLogWriter struct {
+	t     *Tester
+	out   io.Writer
+	start time.Time
+	runID string
+
+	mu sync.Mutex
+}
+
+type logEntry struct {
+	Time    string  `json:"time"`
+	Level   string  `json:"level"`
+	Source  string  `json:"source,omitempty"`
+	Message string  `json:"msg"`
+	RunID   string  `json:"run_id,omitempty"`
+	Phase   string  `json:"phase,omitempty"`
+	Elapsed float64 `json:"elapsed_seconds"`
+	// PhaseElapsed is the time spent in the current phase
+	PhaseElapsed float64 `json:"phase_elapsed_seconds,omitempty"`
+}
+
+// Write is called by klog with one whole entry at a time.
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	now := time.Now()
+	entry := logEntry{
+		Time:    now.UTC().Format(time.RFC3339Nano),
+		Level:   "info",
+		Message: strings.TrimSuffix(string(p), "\n"),
+		RunID:   w.runID,
+		Elapsed: now.Sub(w.start).Seconds(),
+	}
+	if m := klogHeader.FindStringSubmatch(entry.Message); m != nil {
+		entry.Level = klogLevels[m[1]]
+		entry.Source = m[2]
+		entry.Message = entry.Message[len(m[0]):]
+	}
+	if phase, started := w.t.currentPhase(); phase != "" {
+		entry.Phase = phase
+		entry.PhaseElapsed = now.Sub(started).Seconds()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.out.Write(append(data, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,88 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	osexec "os/exec"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// mergeTarget fetches the --merge-target branch and merges it into the
+// checked out revision, the way Prow tests presubmits against the tip of
+// their target branch, so that conflicts fail before anything is built.
+func (t *Tester) mergeTarget(repo *git.Repository, auth transport.AuthMethod) error {
+	branch := plumbing.NewBranchReferenceName(t.MergeTarget)
+	local := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, t.MergeTarget)
+
+	klog.V(0).Infof("Fetching merge target %s", t.MergeTarget)
+	err := retry(t.context(), t.CloneRetries, t.CloneRetryInterval, isTransientGitError, func() error {
+		return repo.FetchContext(t.context(), &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs:   []config.RefSpec{config.RefSpec("+" + branch + ":" + local)},
+			Auth:       auth,
+			Progress:   t.gitProgress("fetch " + t.MergeTarget),
+			Force:      true,
+		})
+	})
+	if errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return fmt.Errorf("merge target branch %q not found in %s", t.MergeTarget, t.redact(t.Repo))
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch merge target %s: %v", t.MergeTarget, t.redact(err.Error()))
+	}
+	target, err := repo.Reference(local, true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve merge target %s: %v", t.MergeTarget, err)
+	}
+
+	if t.VerifySignatures != "" {
+		signer, err := t.verifyCommit(repo, target.Hash())
+		if err != nil {
+			return err
+		}
+		klog.V(0).Infof("Merge target %s at %s is signed by %s", t.MergeTarget, target.Hash(), signer)
+	}
+	if err := addMetadata(map[string]string{"merge-target": t.MergeTarget, "merge-target-commit": target.Hash().String()}); err != nil {
+		return err
+	}
+	return t.mergeRevision(target.Hash().String(), "branch "+t.MergeTarget)
+}
+
+// mergeRevision merges rev, described by what, into the HEAD of the checkout
+// dir. It runs the git CLI, as go-git can't merge. A conflicting merge is
+// aborted and fails with the conflicting files.
+func (t *Tester) mergeRevision(rev, what string) error {
+	if _, err := osexec.LookPath("git"); err != nil {
+		return fmt.Errorf("merging %s requires git: %v", what, err)
+	}
+
+	klog.V(0).Infof("Merging %s (%s)", what, rev)
+	lines, err := t.git("-c", "user.name=kubetest2", "-c", "user.email=kubetest2@localhost",
+		"merge", "--no-ff", "--no-edit", "-m", "Merge "+what, rev)
+	if err == nil {
+		return nil
+	}
+	conflicts, _ := t.git("diff", "--name-only", "--diff-filter=U")
+	if _, abortErr := t.git("merge", "--abort"); abortErr != nil {
+		klog.Warningf("failed to abort the merge of %s: %v", what, abortErr)
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("merging %s conflicts in %s", what, strings.Join(conflicts, ", "))
+	}
+	return fmt.Errorf("failed to merge %s: %v: %s", what, err, strings.Join(lines, "\n"))
+}
+
+// git runs the git CLI with args in the checkout dir and returns its output
+// lines.
+func (t *Tester) git(args ...string) ([]string, error) {
+	cmd := exec.CommandContext(t.context(), "git", args...)
+	cmd.SetDir(t.CheckoutDir)
+	return exec.CombinedOutputLines(cmd)
+}
@@ -0,0 +1,36 @@
+package tester
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// addMetadata merges values into the kubetest2 metadata.json in the
+// artifacts dir, overwriting existing keys.
+func addMetadata(values map[string]string) error {
+	metadataPath := filepath.Join(artifacts.BaseDir(), "metadata.json")
+	meta := map[string]string{}
+	if data, err := os.ReadFile(metadataPath); err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for k, v := range values {
+		meta[k] = v
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(metadataPath), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(metadataPath, data, 0644)
+}
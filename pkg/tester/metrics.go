@@ -0,0 +1,163 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+)
+
+const (
+	metricsPrefix     = "kubetest2_gitremote_"
+	defaultMetricsJob = "kubetest2-tester-gitremote"
+)
+
+// timed runs fn and adds its duration to the duration of phase.
+func (t *Tester) timed(phase string, fn func() error) error {
+	start := time.Now()
+	t.phaseMu.Lock()
+	previous, previousStarted := t.phase, t.phaseStarted
+	t.phase, t.phaseStarted = phase, start
+	t.phaseMu.Unlock()
+
+	err := fn()
+
+	t.phaseMu.Lock()
+	t.phase, t.phaseStarted = previous, previousStarted
+	t.phaseMu.Unlock()
+	t.addDuration(phase, time.Since(start))
+	return err
+}
+
+// addDuration adds d to the duration of phase, which may run several times,
+// e.g. the preflight checks.
+func (t *Tester) addDuration(phase string, d time.Duration) {
+	t.phaseMu.Lock()
+	defer t.phaseMu.Unlock()
+	if t.durations == nil {
+		t.durations = map[string]time.Duration{}
+	}
+	t.durations[phase] += d
+}
+
+// currentPhase returns the running phase and when it started.
+func (t *Tester) currentPhase() (string, time.Time) {
+	t.phaseMu.Lock()
+	defer t.phaseMu.Unlock()
+	return t.phase, t.phaseStarted
+}
+
+// pushMetrics pushes the metrics of a run that ended with err to the
+// --metrics-gateway, replacing the previous metrics of the job.
+func (t *Tester) pushMetrics(err error) error {
+	job := t.MetricsJob
+	if job == "" {
+		job = os.Getenv("JOB_NAME")
+	}
+	if job == "" {
+		job = defaultMetricsJob
+	}
+
+	var b bytes.Buffer
+	writeMetric := func(name, help string, labels map[string]string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n# TYPE %s%s gauge\n", metricsPrefix, name, help, metricsPrefix, name)
+		fmt.Fprintf(&b, "%s%s%s %g\n", metricsPrefix, name, formatLabels(labels), value)
+	}
+
+	result, class := "success", ""
+	if err != nil {
+		result, class = "failure", string(classOf(err))
+	}
+	writeMetric("run_info", "Repo and commit that were tested.", map[string]string{
+		"repo":   t.redact(t.Repo),
+		"commit": t.gitCommit,
+		"run_id": t.runID,
+	}, 1)
+	writeMetric("run_success", "Whether the run succeeded.", map[string]string{"failure_class": class}, boolValue(err == nil))
+	writeMetric("run_timestamp_seconds", "When the run finished.", nil, float64(time.Now().Unix()))
+
+	phases := make([]string, 0, len(t.durations))
+	for phase := range t.durations {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	for _, phase := range phases {
+		writeMetric(phase+"_duration_seconds", "Duration of the "+phase+" phase.", nil, t.durations[phase].Seconds())
+	}
+
+	if t.JUnit {
+		if summary, err := summarizeJUnitReports(); err != nil {
+			klog.Warningf("failed to summarize junit reports for metrics: %v", err)
+		} else {
+			fmt.Fprintf(&b, "# HELP %stests Number of tests by result.\n# TYPE %stests gauge\n", metricsPrefix, metricsPrefix)
+			for _, r := range []struct {
+				result string
+				count  int
+			}{
+				{"passed", summary.Tests - summary.Failures - summary.Skipped},
+				{"failed", summary.Failures},
+				{"skipped", summary.Skipped},
+			} {
+				fmt.Fprintf(&b, "%stests%s %d\n", metricsPrefix, formatLabels(map[string]string{"result": r.result}), r.count)
+			}
+		}
+	}
+
+	pushURL := strings.TrimSuffix(t.MetricsGateway, "/") + "/metrics/" + groupingKey("job", job)
+	klog.V(1).Infof("Pushing %s metrics of job %s to %s", result, job, t.redact(t.MetricsGateway))
+	req, err := http.NewRequestWithContext(t.context(), http.MethodPut, pushURL, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	resp, err := reportClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("PUT %s: %s", t.redact(pushURL), resp.Status)
+	}
+	return nil
+}
+
+// groupingKey returns the pushgateway URL path of a grouping label, base64
+// encoding values that could not be used as a path segment.
+func groupingKey(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+// formatLabels returns labels in the Prometheus text format.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[name])
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, value)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
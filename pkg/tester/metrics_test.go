@@ -0,0 +1,45 @@
+package tester
+
+import "testing"
+
+func TestGroupingKey(t *testing.T) {
+	tests := []struct {
+		name, label, value string
+		want               string
+	}{
+		{name: "plain value", label: "job", value: "e2e", want: "job/e2e"},
+		{name: "escaped value", label: "repo", value: "my repo", want: "repo/my%20repo"},
+		{name: "value with a slash", label: "repo", value: "org/repo", want: "repo@base64/b3JnL3JlcG8"},
+		{name: "empty value", label: "run_id", value: "", want: "run_id@base64/"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := groupingKey(tc.label, tc.value); got != tc.want {
+				t.Errorf("groupingKey(%q, %q) = %q, want %q", tc.label, tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{name: "no labels", want: ""},
+		{name: "sorted", labels: map[string]string{"phase": "test", "branch": "main"}, want: `{branch="main",phase="test"}`},
+		{
+			name:   "escaped",
+			labels: map[string]string{"result": "a \"quoted\" \\ value\nnext"},
+			want:   `{result="a \"quoted\" \\ value\nnext"}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatLabels(tc.labels); got != tc.want {
+				t.Errorf("formatLabels() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/klog"
+)
+
+// cloneWithMirrors clones t.Repo, falling back to each of --repo-mirrors in
+// order when the clone fails, and records the mirror used, if any, in the
+// metadata.
+func (t *Tester) cloneWithMirrors(auth transport.AuthMethod) (*git.Repository, error) {
+	repo, err := t.clone(t.Repo, t.cloneRef(), t.CheckoutDir, auth, t.sparsePaths())
+	if err == nil || len(t.RepoMirrors) == 0 || errors.Is(t.context().Err(), context.Canceled) {
+		return repo, err
+	}
+
+	errs := []string{err.Error()}
+	for _, mirror := range t.RepoMirrors {
+		klog.Warningf("Clone of %s failed, trying mirror %s", t.redact(t.Repo), t.redact(mirror))
+		repo, err = t.clone(mirror, t.cloneRef(), t.CheckoutDir, auth, t.sparsePaths())
+		if err == nil {
+			return repo, addMetadata(map[string]string{"repo-mirror": t.redact(mirror)})
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("failed to clone %s and its mirrors:\n%s", t.redact(t.Repo), strings.Join(errs, "\n"))
+}
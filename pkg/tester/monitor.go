@@ -0,0 +1,106 @@
+package tester
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// processUsage is the resource usage of a process tree.
+type processUsage struct {
+	processes int
+	// cpu is the CPU time used by the live processes of the tree
+	cpu time.Duration
+	rss int64
+}
+
+// monitor samples the resource usage of the process tree of pid and the
+// disk usage of the run dir every --monitor-interval into
+// resource-usage.csv in the artifacts dir, until the returned func is
+// called. Samples are written as they are taken, so that they survive the
+// tester being OOM killed.
+func (t *Tester) monitor(pid int) (stop func()) {
+	if t.MonitorInterval <= 0 {
+		return func() {}
+	}
+	if _, err := treeUsage(pid); err != nil {
+		klog.Warningf("not monitoring the resource usage of the tests: %v", err)
+		return func() {}
+	}
+
+	path := filepath.Join(artifacts.BaseDir(), "resource-usage.csv")
+	f, err := os.Create(path)
+	if err != nil {
+		klog.Warningf("failed to create resource usage timeline: %v", err)
+		return func() {}
+	}
+	klog.V(1).Infof("Sampling the resource usage of the tests every %v into %s", t.MonitorInterval, path)
+
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"time", "elapsed_seconds", "processes", "cpu_seconds", "cpu_percent", "rss_bytes", "disk_used_bytes", "disk_free_bytes"})
+	w.Flush()
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer f.Close()
+		start, last := time.Now(), processUsage{}
+		lastTime := start
+		ticker := time.NewTicker(t.MonitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				usage, err := treeUsage(pid)
+				if err != nil {
+					klog.V(2).Infof("failed to sample resource usage: %v", err)
+					continue
+				}
+				// processes exiting between samples make the CPU time drop
+				cpuPercent := 0.0
+				if delta := usage.cpu - last.cpu; delta > 0 {
+					cpuPercent = 100 * delta.Seconds() / now.Sub(lastTime).Seconds()
+				}
+				last, lastTime = usage, now
+
+				used, free, err := diskUsage(t.runDir)
+				if err != nil {
+					used, free = -1, -1
+				}
+				_ = w.Write([]string{
+					now.UTC().Format(time.RFC3339),
+					strconv.FormatFloat(now.Sub(start).Seconds(), 'f', 0, 64),
+					strconv.Itoa(usage.processes),
+					strconv.FormatFloat(usage.cpu.Seconds(), 'f', 1, 64),
+					strconv.FormatFloat(cpuPercent, 'f', 1, 64),
+					strconv.FormatInt(usage.rss, 10),
+					strconv.FormatInt(used, 10),
+					strconv.FormatInt(free, 10),
+				})
+				w.Flush()
+				if err := w.Error(); err != nil {
+					klog.Warningf("failed to write resource usage timeline: %v", err)
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// errMonitorUnsupported is returned by treeUsage on platforms where it is
+// not implemented.
+var errMonitorUnsupported = fmt.Errorf("resource monitoring is only supported on linux")
@@ -0,0 +1,80 @@
+//go:build linux
+
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the USER_HZ unit of the CPU times in /proc, which is 100 on
+// all the architectures supported by Linux.
+const clockTicks = 100
+
+// treeUsage returns the resource usage of pid and all its descendants.
+func treeUsage(pid int) (processUsage, error) {
+	stats, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return processUsage{}, err
+	}
+
+	type proc struct {
+		ppid       int
+		ticks, rss int64
+	}
+	procs := map[int]proc{}
+	for _, path := range stats {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// the process exited
+			continue
+		}
+		// the command name, in parentheses, may contain spaces
+		end := bytes.LastIndexByte(data, ')')
+		start := bytes.IndexByte(data, ' ')
+		if end < 0 || start < 0 {
+			continue
+		}
+		id, err := strconv.Atoi(string(data[:start]))
+		if err != nil {
+			continue
+		}
+		// fields after the command name, starting with state (3)
+		fields := strings.Fields(string(data[end+1:]))
+		if len(fields) < 22 {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[1])
+		utime, _ := strconv.ParseInt(fields[11], 10, 64)
+		stime, _ := strconv.ParseInt(fields[12], 10, 64)
+		rss, _ := strconv.ParseInt(fields[21], 10, 64)
+		procs[id] = proc{ppid: ppid, ticks: utime + stime, rss: rss * int64(os.Getpagesize())}
+	}
+	if _, ok := procs[pid]; !ok {
+		return processUsage{}, fmt.Errorf("process %d not found in /proc", pid)
+	}
+
+	children := map[int][]int{}
+	for id, p := range procs {
+		children[p.ppid] = append(children[p.ppid], id)
+	}
+	var usage processUsage
+	var ticks int64
+	queue := []int{pid}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		p := procs[id]
+		usage.processes++
+		ticks += p.ticks
+		usage.rss += p.rss
+		queue = append(queue, children[id]...)
+	}
+	usage.cpu = time.Duration(ticks) * time.Second / clockTicks
+	return usage, nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package tester
+
+func treeUsage(pid int) (processUsage, error) {
+	return processUsage{}, errMonitorUnsupported
+}
@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// frameworkNamespaceLabel is set by the e2e framework on the namespaces it
+// creates for the specs.
+const frameworkNamespaceLabel = "e2e-framework"
+
+// namespaceArgs returns the e2e test arguments of the namespace flags. They
+// are only passed when set, as not every suite defines them.
+func (t *Tester) namespaceArgs() []string {
+	var args []string
+	if t.TestNamespace != "" {
+		args = append(args, "--test-namespace="+t.TestNamespace)
+	}
+	if !t.DeleteNamespaceOnFailure {
+		args = append(args, "--delete-namespace-on-failure=false")
+	}
+	return args
+}
+
+// sweepNamespaces deletes the e2e framework namespaces created since start
+// that the tests did not clean up.
+func (t *Tester) sweepNamespaces(start time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.PreflightTimeout)
+	defer cancel()
+
+	out, err := t.kubectlOutput(ctx, "get", "namespaces", "--selector="+frameworkNamespaceLabel, "--output=json")
+	if err != nil {
+		return err
+	}
+	var namespaces struct {
+		Items []struct {
+			Metadata struct {
+				Name              string    `json:"name"`
+				CreationTimestamp time.Time `json:"creationTimestamp"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &namespaces); err != nil {
+		return fmt.Errorf("failed to parse namespaces: %v", err)
+	}
+
+	// creation timestamps have a one second resolution
+	start = start.Truncate(time.Second)
+	var names []string
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase == "Terminating" || ns.Metadata.CreationTimestamp.Before(start) {
+			continue
+		}
+		names = append(names, ns.Metadata.Name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	klog.V(0).Infof("Deleting %d namespaces left behind by the tests: %v", len(names), names)
+	_, err = t.kubectlOutput(ctx, append([]string{"delete", "namespaces", "--wait=false"}, names...)...)
+	return err
+}
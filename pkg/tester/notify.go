@@ -0,0 +1,104 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// reportClient sends the notifications and metrics of a run, with a timeout
+// so that an unresponsive endpoint doesn't hang the end of the run.
+var reportClient = &http.Client{Timeout: 30 * time.Second}
+
+// notification is the summary of a run sent to the --notify-webhook.
+type notification struct {
+	RunID        string `json:"runID"`
+	Repo         string `json:"repo"`
+	Ref          string `json:"ref"`
+	Commit       string `json:"commit"`
+	Result       string `json:"result"`
+	FailureClass string `json:"failureClass,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Duration     string `json:"duration"`
+	ArtifactsURL string `json:"artifactsURL,omitempty"`
+}
+
+// notify POSTs the summary of a run that ended with err after duration to
+// the --notify-webhook.
+func (t *Tester) notify(err error, duration time.Duration) error {
+	n := notification{
+		RunID:        t.runID,
+		Repo:         t.redact(t.Repo),
+		Ref:          t.testedRef(),
+		Commit:       t.gitCommit,
+		Result:       "success",
+		Duration:     duration.Round(time.Second).String(),
+		ArtifactsURL: t.artifactsURL(),
+	}
+	if err != nil {
+		n.Result = "failure"
+		n.FailureClass = string(classOf(err))
+		n.Error = t.redact(err.Error())
+	}
+
+	payload, err := t.notificationPayload(n)
+	if err != nil {
+		return err
+	}
+	klog.V(1).Infof("Sending %s notification to %s", n.Result, t.redact(t.NotifyWebhook))
+	req, err := http.NewRequestWithContext(t.context(), http.MethodPost, t.NotifyWebhook, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%s", t.redact(err.Error()))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := reportClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s", t.redact(err.Error()))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", t.redact(t.NotifyWebhook), resp.Status)
+	}
+	return nil
+}
+
+// notificationPayload renders n with the --notify-template, or as JSON.
+func (t *Tester) notificationPayload(n notification) ([]byte, error) {
+	if t.NotifyTemplate == "" {
+		return json.Marshal(n)
+	}
+	tmpl, err := template.New("notify-template").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(t.NotifyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --notify-template: %v", err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, n); err != nil {
+		return nil, fmt.Errorf("failed to execute --notify-template: %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+// testedRef returns the ref of the repo that was asked for.
+func (t *Tester) testedRef() string {
+	switch {
+	case t.Tag != "":
+		return t.Tag
+	case t.Branch != "" && t.Commit != "":
+		return t.Branch + "@" + t.Commit
+	case t.Branch != "":
+		return t.Branch
+	case t.Commit != "":
+		return t.Commit
+	}
+	return "HEAD"
+}
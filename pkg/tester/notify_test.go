@@ -0,0 +1,53 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	var got notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s request with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	tester := &Tester{runID: "run"}
+	tester.NotifyWebhook = server.URL
+	if err := tester.notify(nil, time.Minute); err != nil {
+		t.Fatalf("notify() failed: %v", err)
+	}
+	if got.RunID != "run" || got.Result != "success" {
+		t.Errorf("got notification %+v", got)
+	}
+}
+
+func TestNotifyCanceled(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	tester := &Tester{ctx: ctx}
+	tester.NotifyWebhook = server.URL
+	start := time.Now()
+	if err := tester.notify(nil, time.Minute); err == nil {
+		t.Error("notify() succeeded after the run context was canceled")
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("notify() returned %v after the run context was canceled", d)
+	}
+}
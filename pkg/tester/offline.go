@@ -0,0 +1,64 @@
+package tester
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// offlineError is the error of a download forbidden by --offline. It names
+// the missing input and how to stage it.
+func offlineError(what, staged string) error {
+	return fmt.Errorf("--offline forbids downloading %s, stage it with %s", what, staged)
+}
+
+// isLocalRepo reports whether url is a file:// URL or a local path, e.g. of a
+// bundle, rather than a remote git URL.
+func isLocalRepo(url string) bool {
+	if strings.HasPrefix(url, "file://") || filepath.IsAbs(url) {
+		return true
+	}
+	if strings.Contains(url, "://") {
+		return false
+	}
+	// scp-like addresses, e.g. git@github.com:org/repo.git
+	host, _, ok := strings.Cut(url, ":")
+	return !ok || strings.Contains(host, "/") || len(host) == 1
+}
+
+// validateOffline checks that the inputs of an --offline run are all local.
+// The downloads that depend on the environment, such as a missing kubectl
+// or Go toolchain, fail when they are attempted.
+func (t *Tester) validateOffline() error {
+	if !t.SkipClone {
+		repos := append([]string{t.Repo}, t.RepoMirrors...)
+		for _, extra := range t.ExtraRepos {
+			url, _, _ := strings.Cut(extra, "#")
+			repos = append(repos, url)
+		}
+		for _, repo := range repos {
+			if !isLocalRepo(repo) {
+				return offlineError("repo "+t.redact(repo), "a local path or file:// URL, or --skip-clone")
+			}
+		}
+		// the providers that fetch the git credentials over the network
+		switch t.GitAuth {
+		case "github-app", "gcp-secret-manager", "vault":
+			return fmt.Errorf("--offline forbids network access, it can't be used with --git-auth=%s", t.GitAuth)
+		}
+	}
+	if t.TestPackageVersion != "" {
+		return offlineError("test package "+t.TestPackageVersion, "the test binaries built from the repo, or --test-binary-path and --ginkgo-binary")
+	}
+	for flag, value := range map[string]string{
+		"--metrics-gateway": t.MetricsGateway,
+		"--notify-webhook":  t.NotifyWebhook,
+		"--artifact-upload": t.ArtifactUpload,
+		"--github-app-id":   t.GithubAppID,
+	} {
+		if value != "" {
+			return fmt.Errorf("--offline forbids network access, it can't be used with %s", flag)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package tester
+
+import "testing"
+
+func TestValidateOffline(t *testing.T) {
+	tests := []struct {
+		name      string
+		repo      string
+		gitAuth   string
+		skipClone bool
+		wantErr   bool
+	}{
+		{name: "local repo", repo: "/src/repo"},
+		{name: "file url", repo: "file:///src/repo", gitAuth: "file"},
+		{name: "remote repo", repo: "https://github.com/org/repo", wantErr: true},
+		{name: "scp-like repo", repo: "git@github.com:org/repo.git", wantErr: true},
+		{name: "vault git auth", repo: "/src/repo", gitAuth: "vault", wantErr: true},
+		{name: "gcp secret manager git auth", repo: "/src/repo", gitAuth: "gcp-secret-manager", wantErr: true},
+		{name: "github app git auth", repo: "/src/repo", gitAuth: "github-app", wantErr: true},
+		{name: "skip clone", repo: "https://github.com/org/repo", gitAuth: "vault", skipClone: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tester := &Tester{}
+			tester.Repo = tc.repo
+			tester.GitAuth = tc.gitAuth
+			tester.SkipClone = tc.skipClone
+			if err := tester.validateOffline(); (err != nil) != tc.wantErr {
+				t.Errorf("validateOffline() = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
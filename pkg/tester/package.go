@@ -0,0 +1,354 @@
+package tester
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// AcquireTestPackage obtains three test binaries and sets up their paths.
+// The first is "ginkgo", the actual ginkgo executable.
+// The second is "e2e.test", which contains the e2e test cases.
+// The third is "kubectl".
+// The binaries are built from the cloned repo unless a test package
+// version was requested, in which case they are downloaded from the release bucket.
+// --test-binary-path overrides the location of the e2e.test binary in both cases.
+func (t *Tester) AcquireTestPackage() error {
+	t.setTestPackagePaths()
+
+	acquire := t.buildTestPackage
+	if t.TestPackageVersion != "" {
+		acquire = func() error { return t.downloadTestPackage(t.context()) }
+	}
+	if err := acquire(); err != nil {
+		return err
+	}
+	return t.checkTestPackage()
+}
+
+// startTestPackageDownload downloads the test package in the background,
+// since it doesn't need the cloned repo. The returned func waits for the
+// download to finish and returns its error. The download is stopped when
+// ctx is canceled, and timed as the download phase since it overlaps the
+// clone.
+func (t *Tester) startTestPackageDownload(ctx context.Context) (wait func() error) {
+	t.setTestPackagePaths()
+	// resolved before the download starts, the version is read while cloning
+	if err := t.resolveTestPackageVersion(ctx); err != nil {
+		return func() error { return err }
+	}
+
+	klog.V(0).Infof("Downloading test package %s while cloning", t.TestPackageVersion)
+	start := time.Now()
+	var (
+		wg  sync.WaitGroup
+		err error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err = t.downloadTestPackage(ctx)
+		d := time.Since(start)
+		t.addDuration("download", d)
+		if err != nil {
+			klog.Warningf("Test package download failed after %v: %v", d.Round(time.Second), err)
+			return
+		}
+		klog.V(0).Infof("Test package download finished in %v", d.Round(time.Second))
+	}()
+	return func() error {
+		wg.Wait()
+		return err
+	}
+}
+
+// checkTestPackage installs ginkgo when missing and checks the test binaries
+// passed by path exist.
+func (t *Tester) checkTestPackage() error {
+	if t.GinkgoBinary == "" {
+		if _, err := os.Stat(t.ginkgoPath); err != nil || t.GinkgoVersion != "" {
+			if t.GinkgoVersion == "" {
+				klog.Warningf("ginkgo was not found at %s, installing it", t.ginkgoPath)
+			}
+			if err := t.installGinkgo(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.TestBinaryPath != "" {
+		if _, err := os.Stat(t.e2eTestPath); err != nil {
+			return fmt.Errorf("failed to find test binary: %v", err)
+		}
+		klog.V(1).Infof("Using test binary or package at %s", t.e2eTestPath)
+	}
+	if t.GinkgoBinary != "" {
+		if _, err := os.Stat(t.ginkgoPath); err != nil {
+			return fmt.Errorf("failed to find ginkgo binary: %v", err)
+		}
+		klog.V(1).Infof("Using ginkgo binary at %s", t.ginkgoPath)
+	}
+	return nil
+}
+
+// setTestPackagePaths sets the paths the test binaries are acquired at.
+func (t *Tester) setTestPackagePaths() {
+	binDir := t.buildOutDir()
+	if t.TestPackageVersion != "" {
+		binDir = t.runDir
+	}
+	t.e2eTestPath = filepath.Join(binDir, "e2e.test")
+	t.ginkgoPath = filepath.Join(binDir, "ginkgo")
+	t.kubectlPath = filepath.Join(binDir, "kubectl")
+	if t.crossBuilding() {
+		t.e2eTestPath = filepath.Join(t.platformBinDir(t.testPlatform()), "e2e.test")
+		t.ginkgoPath = filepath.Join(t.platformBinDir(hostPlatform()), "ginkgo")
+		t.kubectlPath = filepath.Join(t.platformBinDir(hostPlatform()), "kubectl")
+	}
+
+	if t.TestBinaryPath != "" {
+		t.e2eTestPath = t.TestBinaryPath
+		if !filepath.IsAbs(t.e2eTestPath) {
+			t.e2eTestPath = filepath.Join(t.sourceDir(), t.e2eTestPath)
+		}
+	}
+	if t.GinkgoBinary != "" {
+		t.ginkgoPath = t.GinkgoBinary
+		if path, err := osexec.LookPath(t.GinkgoBinary); err == nil {
+			t.ginkgoPath = path
+		}
+	}
+}
+
+// resolveTestPackageVersion replaces the latest --test-package-version with
+// the release named by latest.txt in the release bucket.
+func (t *Tester) resolveTestPackageVersion(ctx context.Context) error {
+	if t.TestPackageVersion != "latest" {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx,
+		"gsutil",
+		"cat",
+		fmt.Sprintf("gs://%s/%s/latest.txt", t.TestPackageBucket, t.TestPackageDir),
+	)
+	lines, err := exec.OutputLines(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get latest release name: %s", err)
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("getting latest release name had no output")
+	}
+	t.TestPackageVersion = lines[0]
+	klog.V(1).Infof("Resolved latest test package version: %s", t.TestPackageVersion)
+	return nil
+}
+
+func (t *Tester) downloadTestPackage(ctx context.Context) error {
+	if err := t.resolveTestPackageVersion(ctx); err != nil {
+		return err
+	}
+
+	releaseTar := fmt.Sprintf("kubernetes-test-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+
+	downloadDir, err := os.UserCacheDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user cache directory: %v", err)
+	}
+	downloadPath := filepath.Join(downloadDir, t.TestPackageVersion, releaseTar)
+	if err := os.MkdirAll(filepath.Dir(downloadPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := t.ensureReleaseTar(ctx, downloadPath, releaseTar); err != nil {
+		return err
+	}
+	if err := t.extractBinaries(downloadPath); err != nil {
+		return err
+	}
+
+	return t.ensureKubectl(ctx, t.kubectlPath)
+}
+
+func (t *Tester) extractBinaries(downloadPath string) error {
+	f, err := os.Open(downloadPath)
+	if err != nil {
+		return fmt.Errorf("failed to open downloaded tar at %s: %s", downloadPath, err)
+	}
+	defer f.Close()
+	gzf, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip reader: %s", err)
+	}
+	defer gzf.Close()
+
+	tarReader := tar.NewReader(gzf)
+
+	// Map of paths in archive to destination paths
+	extract := map[string]string{
+		"kubernetes/test/bin/e2e.test": filepath.Join(t.runDir, "e2e.test"),
+	}
+	// Never overwrite a ginkgo binary passed with --ginkgo-binary.
+	if t.GinkgoBinary == "" {
+		extract["kubernetes/test/bin/ginkgo"] = t.ginkgoPath
+	}
+	extracted := map[string]bool{}
+
+	for len(extracted) < len(extract) {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error during tar read: %s", err)
+		}
+
+		dest := extract[header.Name]
+		if dest == "" {
+			continue
+		}
+		if err := extractFile(tarReader, dest); err != nil {
+			return err
+		}
+		extracted[header.Name] = true
+	}
+
+	for path := range extract {
+		if !extracted[path] {
+			return fmt.Errorf("failed to find %s in %s", path, downloadPath)
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, dest string) error {
+	outFile, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0700)
+	if err != nil {
+		return fmt.Errorf("error creating file at %s: %s", dest, err)
+	}
+	defer outFile.Close()
+
+	if _, err := io.Copy(outFile, r); err != nil {
+		return fmt.Errorf("error reading data from tar with header name %s: %s", dest, err)
+	}
+	return nil
+}
+
+// ensureKubectl checks if the kubectl exists and verifies the hashes
+// else downloads it from GCS
+func (t *Tester) ensureKubectl(ctx context.Context, downloadPath string) error {
+	kubectlPathInGCS := fmt.Sprintf(
+		"gs://%s/%s/%s/bin/%s/%s/kubectl",
+		t.TestPackageBucket,
+		t.TestPackageDir,
+		t.TestPackageVersion,
+		runtime.GOOS,
+		runtime.GOARCH,
+	)
+	if _, err := os.Stat(downloadPath); err == nil {
+		klog.V(0).Infof("Found existing kubectl at %v", downloadPath)
+		err := t.compareSHA(ctx, downloadPath, kubectlPathInGCS, "")
+		if err == nil {
+			klog.V(0).Infof("Validated hash for existing kubectl at %v", downloadPath)
+			return nil
+		}
+		klog.Warning(err)
+	}
+
+	cmd := exec.CommandContext(ctx, "gsutil", "cp", kubectlPathInGCS, downloadPath)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to download kubectl for release %s: %s", t.TestPackageVersion, err)
+	}
+	if err := t.compareSHA(ctx, downloadPath, kubectlPathInGCS, ""); err != nil {
+		os.Remove(downloadPath)
+		return fmt.Errorf("refusing to use the downloaded kubectl: %v", err)
+	}
+	if err := os.Chmod(downloadPath, 0700); err != nil {
+		return fmt.Errorf("failed to make %s executable: %s", downloadPath, err)
+	}
+	return nil
+}
+
+// ensureReleaseTar checks if the kubernetes test tarball already exists
+// and verifies the hashes
+// else downloads it from GCS
+func (t *Tester) ensureReleaseTar(ctx context.Context, downloadPath, releaseTar string) error {
+	releaseTarPathInGCS := fmt.Sprintf(
+		"gs://%s/%s/%s/%s",
+		t.TestPackageBucket,
+		t.TestPackageDir,
+		t.TestPackageVersion,
+		releaseTar,
+	)
+
+	if _, err := os.Stat(downloadPath); err == nil {
+		klog.V(0).Infof("Found existing tar at %v", downloadPath)
+		err := t.compareSHA(ctx, downloadPath, releaseTarPathInGCS, t.TestPackageChecksum)
+		if err == nil {
+			klog.V(0).Infof("Validated hash for existing tar at %v", downloadPath)
+			return nil
+		}
+		klog.Warning(err)
+	}
+
+	cmd := exec.CommandContext(ctx, "gsutil", "cp", releaseTarPathInGCS, downloadPath)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to download release tar %s for release %s: %s", releaseTar, t.TestPackageVersion, err)
+	}
+	if err := t.compareSHA(ctx, downloadPath, releaseTarPathInGCS, t.TestPackageChecksum); err != nil {
+		// never leave a tampered tar in the cache
+		os.Remove(downloadPath)
+		return fmt.Errorf("refusing to use the downloaded release tar: %v", err)
+	}
+	return nil
+}
+
+// compareSHA checks the sha256 of downloadPath against expectedSHA or, when
+// empty, against the sha256 published next to gcsFilePath.
+func (t *Tester) compareSHA(ctx context.Context, downloadPath string, gcsFilePath string, expectedSHA string) error {
+	if expectedSHA == "" {
+		cmd := exec.CommandContext(ctx, "gsutil", "cat", gcsFilePath+".sha256")
+		expectedSHABytes, err := exec.Output(cmd)
+		if err != nil {
+			return fmt.Errorf("failed to get sha256 for file %s for release %s: %s", gcsFilePath, t.TestPackageVersion, err)
+		}
+		expectedSHA = strings.TrimSpace(string(expectedSHABytes))
+	}
+	actualSHA, err := sha256sum(downloadPath)
+	if err != nil {
+		return fmt.Errorf("failed to compute sha256 for %q: %v", downloadPath, err)
+	}
+	if !strings.EqualFold(actualSHA, expectedSHA) {
+		return fmt.Errorf("sha256 does not match for %s: got %s, expected %s", downloadPath, actualSHA, expectedSHA)
+	}
+	return nil
+}
+
+func sha256sum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
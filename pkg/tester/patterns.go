@@ -0,0 +1,88 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// loadPatternFiles adds the patterns of --focus-file, and of --skip-file and
+// --skip-url, to the focus and skip regexes, and focuses on the specs of
+// --rerun-failed-from.
+func (t *Tester) loadPatternFiles() error {
+	for _, f := range []struct {
+		flag  string
+		files []string
+		regex *string
+	}{
+		{"--focus-file", t.FocusFile, &t.FocusRegex},
+		{"--skip-file", t.SkipFile, &t.SkipRegex},
+	} {
+		patterns := []string{}
+		if *f.regex != "" {
+			patterns = append(patterns, *f.regex)
+		}
+		for _, path := range f.files {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(t.sourceDir(), path)
+			}
+			filePatterns, err := readPatternFile(path)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", f.flag, err)
+			}
+			klog.V(1).Infof("Read %d patterns from %s", len(filePatterns), path)
+			patterns = append(patterns, filePatterns...)
+		}
+		if f.flag == "--skip-file" {
+			listPatterns, err := t.skipListPatterns()
+			if err != nil {
+				return err
+			}
+			patterns = append(patterns, listPatterns...)
+		}
+		*f.regex = strings.Join(patterns, "|")
+	}
+
+	if t.RerunFailedFrom != "" {
+		names, err := failedSpecs(t.RerunFailedFrom)
+		if err != nil {
+			return fmt.Errorf("invalid --rerun-failed-from: %v", err)
+		}
+		klog.V(0).Infof("Rerunning %d failed specs of %s", len(names), t.RerunFailedFrom)
+		if t.FocusRegex != "" {
+			klog.V(1).Infof("--rerun-failed-from replaces the focus regex %q", t.FocusRegex)
+		}
+		focus := make([]string, len(names))
+		for i, name := range names {
+			focus[i] = regexp.QuoteMeta(name)
+		}
+		t.FocusRegex = strings.Join(focus, "|")
+		t.rerunNothing = len(names) == 0
+	}
+	return nil
+}
+
+// readPatternFile reads the newline separated regular expressions of path.
+// Blank lines and lines starting with # are ignored.
+func readPatternFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var patterns []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := regexp.Compile(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, nil
+}
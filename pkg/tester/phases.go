@@ -0,0 +1,58 @@
+package tester
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// phaseOrder is the order the phases run in, see timed(). The download of
+// --test-package-version runs during the clone, so their sum can exceed the
+// total.
+var phaseOrder = []string{"flags", "clone", "download", "build", "preflight", "hooks", "test", "artifacts"}
+
+// summarizePhases logs a table of the phase durations of a run that took
+// total, and records them in the kubetest2 metadata as duration-<phase>.
+func (t *Tester) summarizePhases(total time.Duration) {
+	t.phaseMu.Lock()
+	durations := map[string]time.Duration{}
+	for phase, d := range t.durations {
+		durations[phase] = d
+	}
+	t.phaseMu.Unlock()
+	// the flags are parsed before the run starts
+	total += durations["flags"]
+
+	phases := []string{}
+	for _, phase := range phaseOrder {
+		if _, ok := durations[phase]; ok {
+			phases = append(phases, phase)
+		}
+	}
+	// phases added by later code paths that are not in phaseOrder
+	var others []string
+	for phase := range durations {
+		known := false
+		for _, p := range phaseOrder {
+			known = known || p == phase
+		}
+		if !known {
+			others = append(others, phase)
+		}
+	}
+	sort.Strings(others)
+	phases = append(phases, others...)
+
+	meta := map[string]string{"duration-total": total.Round(time.Millisecond).String()}
+	klog.V(0).Infof("Phase timings:")
+	for _, phase := range phases {
+		d := durations[phase].Round(time.Millisecond)
+		meta["duration-"+phase] = d.String()
+		klog.V(0).Infof("  %-10s %12s %5.1f%%", phase, d, 100*durations[phase].Seconds()/total.Seconds())
+	}
+	klog.V(0).Infof("  %-10s %12s", "total", total.Round(time.Millisecond))
+	if err := addMetadata(meta); err != nil {
+		klog.Warningf("failed to write phase timings to metadata: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package tester
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// crossBuildOutDir is where the kubernetes build places the binaries of each
+// platform, in <os>/<arch> subdirectories, when building for several.
+const crossBuildOutDir = "_output/local/bin"
+
+// hostPlatform returns the os/arch of the tester.
+func hostPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// testPlatform returns the os/arch the e2e test binary is built for: the one
+// of --build-goos and --build-goarch, or else the first non host platform of
+// $KUBE_BUILD_PLATFORMS, or else the host platform.
+func (t *Tester) testPlatform() string {
+	if t.BuildGOOS != "" || t.BuildGOARCH != "" {
+		goos, goarch := runtime.GOOS, runtime.GOARCH
+		if t.BuildGOOS != "" {
+			goos = t.BuildGOOS
+		}
+		if t.BuildGOARCH != "" {
+			goarch = t.BuildGOARCH
+		}
+		return goos + "/" + goarch
+	}
+	for _, platform := range strings.Fields(envValue(t.env, "KUBE_BUILD_PLATFORMS")) {
+		if platform != hostPlatform() {
+			return platform
+		}
+	}
+	return hostPlatform()
+}
+
+// crossBuilding reports whether the e2e test binary is built for another
+// platform than the tester's. ginkgo and kubectl are still built for the
+// host, since they run on it.
+func (t *Tester) crossBuilding() bool {
+	return t.TestPackageVersion == "" && t.testPlatform() != hostPlatform()
+}
+
+// platformBinDir returns the directory the kubernetes build places the
+// binaries of platform in.
+func (t *Tester) platformBinDir(platform string) string {
+	return filepath.Join(t.sourceDir(), crossBuildOutDir, filepath.FromSlash(platform))
+}
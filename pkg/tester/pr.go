@@ -0,0 +1,60 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"k8s.io/klog"
+)
+
+// pullRequestRef returns the ref of the head of pull request pr in the
+// remote at url: refs/merge-requests/<pr>/head on GitLab, and
+// refs/pull/<pr>/head otherwise.
+func pullRequestRef(url string, pr int) plumbing.ReferenceName {
+	if strings.Contains(strings.ToLower(url), "gitlab") {
+		return plumbing.ReferenceName(fmt.Sprintf("refs/merge-requests/%d/head", pr))
+	}
+	return plumbing.ReferenceName(fmt.Sprintf("refs/pull/%d/head", pr))
+}
+
+// checkoutPullRequest fetches the head of the --pr and checks it out, or
+// merges it into the cloned base branch with --pr-merge.
+func (t *Tester) checkoutPullRequest(repo *git.Repository, auth transport.AuthMethod) error {
+	ref := pullRequestRef(t.Repo, t.PR)
+	local := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/pr/%d", git.DefaultRemoteName, t.PR))
+
+	klog.V(0).Infof("Fetching pull request #%d (%s)", t.PR, ref)
+	err := retry(t.context(), t.CloneRetries, t.CloneRetryInterval, isTransientGitError, func() error {
+		return repo.FetchContext(t.context(), &git.FetchOptions{
+			RemoteName: git.DefaultRemoteName,
+			RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + local)},
+			Auth:       auth,
+			Progress:   t.gitProgress("fetch pull request " + strconv.Itoa(t.PR)),
+			Force:      true,
+		})
+	})
+	if errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return fmt.Errorf("pull request #%d not found in %s", t.PR, t.redact(t.Repo))
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("failed to fetch pull request #%d: %v", t.PR, t.redact(err.Error()))
+	}
+	head, err := repo.Reference(local, true)
+	if err != nil {
+		return fmt.Errorf("failed to resolve pull request #%d: %v", t.PR, err)
+	}
+
+	if err := addMetadata(map[string]string{"pull-request": strconv.Itoa(t.PR), "pull-request-head": head.Hash().String()}); err != nil {
+		return err
+	}
+	if t.PRMerge {
+		return t.mergeRevision(head.Hash().String(), fmt.Sprintf("pull request #%d", t.PR))
+	}
+	return checkoutRevision(repo, head.Hash().String(), t.sparsePaths())
+}
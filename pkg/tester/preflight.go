@@ -0,0 +1,126 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+const (
+	kib = int64(1) << 10
+	mib = kib << 10
+	gib = mib << 10
+	tib = gib << 10
+)
+
+// errDiskUsageUnsupported is returned by freeDiskSpace on platforms where it
+// is not implemented.
+var errDiskUsageUnsupported = errors.New("checking free disk space is not supported on this platform")
+
+// checkDiskSpace fails fast when the filesystem of the checkout dir has less
+// free space than --min-disk, rather than letting the clone or the build die
+// with an opaque I/O error.
+func (t *Tester) checkDiskSpace() error {
+	required, err := t.minDisk()
+	if err != nil {
+		return err
+	}
+	if required <= 0 {
+		return nil
+	}
+
+	// the checkout dir usually doesn't exist yet
+	dir := t.CheckoutDir
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeDiskSpace(dir)
+	if errors.Is(err, errDiskUsageUnsupported) {
+		klog.Warning(err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check free disk space of %s: %v", dir, err)
+	}
+
+	klog.V(1).Infof("%s has %s free, %s required", dir, formatSize(free), formatSize(required))
+	if free < required {
+		return fmt.Errorf("not enough disk space in %s: %s free, at least %s (--min-disk) required", dir, formatSize(free), formatSize(required))
+	}
+	return nil
+}
+
+// minDisk returns the free space required by --min-disk, or estimated from
+// what will be cloned, built and downloaded when it is empty.
+func (t *Tester) minDisk() (int64, error) {
+	if t.MinDisk != "" {
+		size, err := parseSize(t.MinDisk)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --min-disk: %v", err)
+		}
+		return size, nil
+	}
+
+	var required int64
+	if !t.SkipClone {
+		// a full clone of kubernetes/kubernetes is about 1.5Gi
+		required += 2 * gib
+	}
+	switch {
+	case t.RunMode != runModeGinkgo || t.BuildCmd == "":
+	case t.TestPackageVersion != "":
+		// the test and kubectl release tars, and what is extracted from them
+		required += 2 * gib
+	case repoName(t.Repo) == "kubernetes":
+		// build outputs and the go build cache
+		required += 20 * gib
+	default:
+		required += 5 * gib
+	}
+	return required, nil
+}
+
+// parseSize parses sizes such as 512Mi, 20Gi or 1T, with binary units, in
+// bytes.
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"Ki", kib}, {"Mi", mib}, {"Gi", gib}, {"Ti", tib},
+		{"K", kib}, {"M", mib}, {"G", gib}, {"T", tib},
+	}
+	number, unit := strings.TrimSpace(s), int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSuffix(number, u.suffix), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q, must be a number of bytes optionally suffixed with Ki, Mi, Gi or Ti", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// formatSize formats bytes with the largest binary unit that fits.
+func formatSize(bytes int64) string {
+	for _, u := range []struct {
+		suffix string
+		size   int64
+	}{{"Ti", tib}, {"Gi", gib}, {"Mi", mib}, {"Ki", kib}} {
+		if bytes >= u.size {
+			return strconv.FormatFloat(float64(bytes)/float64(u.size), 'f', 1, 64) + u.suffix
+		}
+	}
+	return strconv.FormatInt(bytes, 10) + "B"
+}
@@ -0,0 +1,17 @@
+package tester
+
+import (
+	"errors"
+	"time"
+)
+
+// errTesterTimeout is returned when the test process had to be terminated
+// by the tester, as opposed to the tests failing.
+var errTesterTimeout = errors.New("tester timeout exceeded")
+
+// testDeadline is how long the test process may run before the tester
+// terminates it. The margin on top of --timeout leaves ginkgo time to
+// enforce its own timeout and write its reports.
+func (t *Tester) testDeadline() time.Duration {
+	return t.Timeout + t.TimeoutMargin
+}
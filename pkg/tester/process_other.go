@@ -0,0 +1,49 @@
+//go:build !unix
+
+package tester
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// runTestCmd runs cmd and kills it when it outlives the process deadline,
+// see testDeadline(), or when the run is canceled. Signals are only
+// forwarded to the test process group on unix systems.
+func (t *Tester) runTestCmd(cmd exec.Cmd) error {
+	local, ok := cmd.(*exec.LocalCmd)
+	if !ok {
+		return cmd.Run()
+	}
+	c := local.Cmd
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+	stopMonitor := t.monitor(c.Process.Pid)
+	defer stopMonitor()
+	stopHeartbeat := t.heartbeat()
+	defer stopHeartbeat()
+	wait := make(chan error, 1)
+	go func() {
+		wait <- c.Wait()
+	}()
+
+	select {
+	case err := <-wait:
+		return err
+	case <-t.context().Done():
+		klog.Warningf("run canceled, killing the test process")
+		_ = c.Process.Kill()
+		<-wait
+		return fmt.Errorf("test process terminated: %w", t.context().Err())
+	case <-time.After(t.testDeadline()):
+		klog.Warningf("test process still running after %v, killing it", t.testDeadline())
+		_ = c.Process.Kill()
+		<-wait
+		return fmt.Errorf("%w: %v", errTesterTimeout, t.testDeadline())
+	}
+}
@@ -0,0 +1,89 @@
+//go:build unix
+
+package tester
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// runTestCmd runs cmd in its own process group. SIGINT and SIGTERM are
+// forwarded to the whole group, so ginkgo and its parallel nodes can flush
+// their reports, and the group is killed if it is still running after the
+// grace period. The group is likewise terminated when it outlives the
+// process deadline, see testDeadline(), or when the run is canceled.
+func (t *Tester) runTestCmd(cmd exec.Cmd) error {
+	local, ok := cmd.(*exec.LocalCmd)
+	if !ok {
+		return cmd.Run()
+	}
+	c := local.Cmd
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+	stopMonitor := t.monitor(c.Process.Pid)
+	defer stopMonitor()
+	stopHeartbeat := t.heartbeat()
+	defer stopHeartbeat()
+	wait := make(chan error, 1)
+	go func() {
+		wait <- c.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.testDeadline())
+	defer cancel()
+	deadline := ctx.Done()
+
+	var kill <-chan time.Time
+	timedOut := false
+	canceled := t.context().Done()
+	for {
+		select {
+		case <-canceled:
+			klog.Warningf("run canceled, terminating the test process group")
+			_ = syscall.Kill(-c.Process.Pid, syscall.SIGTERM)
+			if kill == nil {
+				kill = time.After(t.SignalGracePeriod)
+			}
+			canceled = nil
+		case sig := <-signals:
+			klog.Warningf("received %v, forwarding it to the test process group", sig)
+			_ = syscall.Kill(-c.Process.Pid, sig.(syscall.Signal))
+			if kill == nil {
+				kill = time.After(t.SignalGracePeriod)
+			}
+		case <-deadline:
+			klog.Warningf("test process group still running after %v, terminating it", t.testDeadline())
+			timedOut = true
+			_ = syscall.Kill(-c.Process.Pid, syscall.SIGTERM)
+			if kill == nil {
+				kill = time.After(t.SignalGracePeriod)
+			}
+			deadline = nil
+		case <-kill:
+			klog.Warningf("test process group still running after %v, killing it", t.SignalGracePeriod)
+			_ = syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+		case err := <-wait:
+			if timedOut {
+				return fmt.Errorf("%w: %v", errTesterTimeout, t.testDeadline())
+			}
+			if ctxErr := t.context().Err(); ctxErr != nil {
+				return fmt.Errorf("test process terminated: %w", ctxErr)
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package tester
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"k8s.io/klog"
+)
+
+// progressInterval is how often the progress of clones and fetches is logged.
+const progressInterval = 10 * time.Second
+
+// gitProgress returns the progress writer of a clone or fetch of what, nil
+// with --quiet-clone.
+func (t *Tester) gitProgress(what string) sideband.Progress {
+	if t.QuietClone {
+		return nil
+	}
+	return &progressLogger{prefix: what, interval: progressInterval, last: time.Now()}
+}
+
+// progressLogger logs the latest progress message sent by the git server,
+// such as "Receiving objects: 45% (450/1000), 1.20 MiB | 2.00 MiB/s", at
+// most once per interval, so that long clones don't look hung.
+type progressLogger struct {
+	prefix   string
+	interval time.Duration
+	last     time.Time
+	pending  []byte
+}
+
+func (p *progressLogger) Write(b []byte) (int, error) {
+	p.pending = append(p.pending, b...)
+	// messages end with \r while they are being updated, and with \n once
+	// they are final
+	end := bytes.LastIndexAny(p.pending, "\r\n")
+	if end < 0 {
+		return len(b), nil
+	}
+	messages := strings.FieldsFunc(string(p.pending[:end]), func(r rune) bool {
+		return r == '\r' || r == '\n'
+	})
+	p.pending = append([]byte(nil), p.pending[end+1:]...)
+
+	if len(messages) == 0 || time.Since(p.last) < p.interval {
+		return len(b), nil
+	}
+	p.last = time.Now()
+	klog.V(0).Infof("%s: %s", p.prefix, strings.TrimSpace(messages[len(messages)-1]))
+	return len(b), nil
+}
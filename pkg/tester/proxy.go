@@ -0,0 +1,75 @@
+package tester
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/klog"
+)
+
+// configureGitTransport installs the HTTP(S) transport of git when it needs
+// a proxy or TLS settings, which then apply to the repo, its submodules,
+// the extra repos and the clone cache alike.
+//
+// --git-proxy routes the HTTP(S) git traffic in place of $HTTPS_PROXY and
+// $HTTP_PROXY, except for the hosts in $NO_PROXY. Without it git, like the
+// binary downloads, uses the proxy env variables. SSH remotes honor
+// $ALL_PROXY.
+func (t *Tester) configureGitTransport() error {
+	if t.GitProxy == "" && t.GitCABundle == "" && !t.GitInsecureSkipVerify {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if t.GitProxy != "" {
+		proxyURL, err := url.Parse(t.GitProxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid --git-proxy %q, must be a URL such as http://proxy:3128", t.GitProxy)
+		}
+		klog.V(0).Infof("Using proxy %s for git", proxyURL.Redacted())
+
+		config := httpproxy.FromEnvironment()
+		config.HTTPProxy = t.GitProxy
+		config.HTTPSProxy = t.GitProxy
+		proxyFunc := config.ProxyFunc()
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFunc(req.URL)
+		}
+	}
+
+	if t.GitCABundle != "" || t.GitInsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if t.GitCABundle != "" {
+		pem, err := os.ReadFile(t.GitCABundle)
+		if err != nil {
+			return fmt.Errorf("failed to read --git-ca-bundle: %v", err)
+		}
+		// the bundle is trusted in addition to the system roots
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in --git-ca-bundle %s", t.GitCABundle)
+		}
+		klog.V(1).Infof("Using CA bundle %s for git", t.GitCABundle)
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if t.GitInsecureSkipVerify {
+		klog.Warningf("not verifying the TLS certificates of git servers")
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+
+	gitClient := githttp.NewClient(&http.Client{Transport: transport})
+	client.InstallProtocol("http", gitClient)
+	client.InstallProtocol("https", gitClient)
+	return nil
+}
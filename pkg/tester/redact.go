@@ -0,0 +1,104 @@
+package tester
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultRedactPattern matches the names of env variables and flags that
+// commonly hold credentials.
+const defaultRedactPattern = "TOKEN|PASSWORD|KEY|SECRET"
+
+// minSecretLength is the length of the shortest env value secrets() masks,
+// shorter values, e.g. true or a path, would mask unrelated text.
+const minSecretLength = 8
+
+// assignment matches NAME=VALUE pairs, e.g. env entries or --flag=value
+// arguments.
+var assignment = regexp.MustCompile(`([A-Za-z0-9_.-]+)=(\S+)`)
+
+// compileRedactPattern compiles --redact-pattern, matched case-insensitively.
+func (t *Tester) compileRedactPattern() error {
+	if t.RedactPattern == "" {
+		t.redactRegexp = nil
+		return nil
+	}
+	re, err := regexp.Compile("(?i)" + t.RedactPattern)
+	if err != nil {
+		return fmt.Errorf("invalid --redact-pattern: %v", err)
+	}
+	t.redactRegexp = re
+	return nil
+}
+
+// secrets returns the credentials to mask: the git token, the
+// --env-from-secret values and the values of the --env and --env-file
+// entries whose names match --redact-pattern. The inherited environment is
+// not scanned, most of its matching values are not secrets.
+func (t *Tester) secrets() []string {
+	var secrets []string
+	if t.GitToken != "" {
+		secrets = append(secrets, t.GitToken)
+	}
+	if t.Token != "" {
+		secrets = append(secrets, t.Token)
+	}
+	for _, kv := range t.secretEnv {
+		_, value, _ := strings.Cut(kv, "=")
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	if t.githubApp != nil {
+		t.githubApp.mu.Lock()
+		if t.githubApp.token != "" {
+			secrets = append(secrets, t.githubApp.token)
+		}
+		t.githubApp.mu.Unlock()
+	}
+	if t.redactRegexp == nil {
+		return secrets
+	}
+	// the entries passed to the tests, before resolveEnv() the raw --env
+	env := t.envOverrides
+	if env == nil {
+		env = t.Env
+	}
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if len(value) >= minSecretLength && t.redactRegexp.MatchString(name) {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// redact masks any configured credentials in s before it is logged, along
+// with the values of NAME=VALUE pairs whose names match --redact-pattern.
+func (t *Tester) redact(s string) string {
+	for _, secret := range t.secrets() {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	if t.redactRegexp != nil {
+		s = assignment.ReplaceAllStringFunc(s, func(kv string) string {
+			name, _, _ := strings.Cut(kv, "=")
+			if t.redactRegexp.MatchString(name) {
+				return name + "=" + redacted
+			}
+			return kv
+		})
+	}
+	return s
+}
+
+// redactAll is like redact but for a list of values, e.g. command arguments.
+func (t *Tester) redactAll(values []string) []string {
+	redacted := make([]string, len(values))
+	for i, v := range values {
+		redacted[i] = t.redact(v)
+	}
+	return redacted
+}
@@ -0,0 +1,109 @@
+package tester
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// maxListedRefs caps the number of branches and tags listed in errors.
+const maxListedRefs = 20
+
+// validateRefs checks the combination of ref flags. The revision tested is,
+// in order of precedence: --commit, checked out after cloning --branch or the
+// remote default branch, then --tag, then the tip of --branch, and finally
+// the tip of the remote default branch.
+func (t *Tester) validateRefs() error {
+	t.Branch = strings.TrimPrefix(t.Branch, "refs/heads/")
+	t.Tag = strings.TrimPrefix(t.Tag, "refs/tags/")
+	t.MergeTarget = strings.TrimPrefix(t.MergeTarget, "refs/heads/")
+
+	switch {
+	case t.Tag != "" && t.Branch != "":
+		return fmt.Errorf("--tag and --branch are mutually exclusive")
+	case t.Tag != "" && t.Commit != "":
+		return fmt.Errorf("--tag and --commit are mutually exclusive")
+	case t.SkipClone && (t.Tag != "" || t.Branch != "" || t.Commit != "" || t.PR != 0):
+		return fmt.Errorf("--branch, --tag, --commit and --pr can't be used with --skip-clone")
+	case t.SkipClone && (len(t.RepoMirrors) > 0 || t.VerifySignatures != ""):
+		return fmt.Errorf("--repo-mirrors and --verify-signatures can't be used with --skip-clone")
+	case t.PR < 0:
+		return fmt.Errorf("invalid --pr %d", t.PR)
+	case t.PR > 0 && (t.Tag != "" || t.Commit != ""):
+		return fmt.Errorf("--pr can't be used with --tag or --commit")
+	case t.PRMerge && t.PR == 0:
+		return fmt.Errorf("--pr-merge requires --pr")
+	case t.PRMerge && len(t.SparsePaths) > 0:
+		return fmt.Errorf("--pr-merge requires a full checkout, it can't be used with --sparse-paths")
+	case t.MergeTarget != "" && (t.SkipClone || len(t.SparsePaths) > 0):
+		return fmt.Errorf("--merge-target requires a full clone, it can't be used with --skip-clone or --sparse-paths")
+	case t.MergeTarget != "" && t.PRMerge:
+		return fmt.Errorf("--merge-target and --pr-merge are mutually exclusive")
+	}
+	for name, ref := range map[string]string{"--branch": t.Branch, "--tag": t.Tag, "--merge-target": t.MergeTarget} {
+		if ref != "" && !validRefName(ref) {
+			return fmt.Errorf("invalid %s %q", name, ref)
+		}
+	}
+	return nil
+}
+
+// validRefName reports whether name is a valid branch or tag name, following
+// the main rules of git check-ref-format.
+func validRefName(name string) bool {
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+		strings.HasSuffix(name, ".lock") || strings.HasSuffix(name, ".") ||
+		strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//") {
+		return false
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// missingRefError returns the error for ref not existing in the remote at
+// url. It lists the branches and tags the remote has, to spot typos.
+func (t *Tester) missingRefError(url string, ref plumbing.ReferenceName, auth transport.AuthMethod) error {
+	kind := "branch"
+	if ref.IsTag() {
+		kind = "tag"
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	refs, err := remote.ListContext(t.context(), &git.ListOptions{Auth: auth})
+	if err != nil {
+		return fmt.Errorf("%s %q not found in %s (failed to list remote refs: %v)", kind, ref.Short(), t.redact(url), t.redact(err.Error()))
+	}
+
+	var branches, tags []string
+	for _, r := range refs {
+		switch {
+		case r.Name().IsBranch():
+			branches = append(branches, r.Name().Short())
+		case r.Name().IsTag():
+			tags = append(tags, r.Name().Short())
+		}
+	}
+	return fmt.Errorf("%s %q not found in %s, the remote has branches %s and tags %s",
+		kind, ref.Short(), t.redact(url), listRefs(branches), listRefs(tags))
+}
+
+// listRefs formats names sorted and capped at maxListedRefs.
+func listRefs(names []string) string {
+	sort.Strings(names)
+	if len(names) > maxListedRefs {
+		return fmt.Sprintf("[%s ... (%d more)]", strings.Join(names[:maxListedRefs], " "), len(names)-maxListedRefs)
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
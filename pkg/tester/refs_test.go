@@ -0,0 +1,40 @@
+package tester
+
+import "testing"
+
+func TestValidRefName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "main", want: true},
+		{name: "release-1.29", want: true},
+		{name: "feature/nested/branch", want: true},
+		{name: "v1.2.3", want: true},
+		{name: "-leading-dash"},
+		{name: "/leading-slash"},
+		{name: "trailing-slash/"},
+		{name: "double//slash"},
+		{name: "branch.lock"},
+		{name: "trailing-dot."},
+		{name: "dot..dot"},
+		{name: "at@{brace"},
+		{name: "with space"},
+		{name: "tilde~1"},
+		{name: "caret^"},
+		{name: "colon:"},
+		{name: "question?"},
+		{name: "star*"},
+		{name: "bracket["},
+		{name: `back\slash`},
+		{name: "control\x01"},
+		{name: "del\x7f"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := validRefName(tc.name); got != tc.want {
+				t.Errorf("validRefName(%q) = %v, want %v", tc.name, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// readGinkgoReport parses the ginkgo v2 JSON report at path. Errors
+// reading it satisfy os.IsNotExist when there is none.
+func readGinkgoReport(path string) (ginkgoReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report ginkgoReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse ginkgo report %s: %v", path, err)
+	}
+	return report, nil
+}
+
+// writeReportSummary summarizes the ginkgo JSON report of --json-report in
+// the metadata, with what junit doesn't record: the labels of the failed
+// specs, the specs that needed several attempts and the time line.
+func (t *Tester) writeReportSummary() error {
+	path := t.ginkgoJSONReportPath()
+	report, err := readGinkgoReport(path)
+	if os.IsNotExist(err) {
+		// ginkgo v1, or ginkgo did not get to write its report
+		klog.V(1).Infof("No ginkgo report at %s, not summarizing it", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	states := map[string]int{}
+	failedLabels := map[string]bool{}
+	var start, end time.Time
+	var retried int
+	var slowest string
+	var slowestTime time.Duration
+	for _, suite := range report {
+		if start.IsZero() || (!suite.StartTime.IsZero() && suite.StartTime.Before(start)) {
+			start = suite.StartTime
+		}
+		if suite.EndTime.After(end) {
+			end = suite.EndTime
+		}
+		for _, r := range suite.SpecReports {
+			if r.LeafNodeType != "It" {
+				continue
+			}
+			states[r.State]++
+			if r.NumAttempts > 1 {
+				retried++
+			}
+			if r.RunTime > slowestTime {
+				slowest, slowestTime = strings.Join(append(r.ContainerHierarchyTexts, r.LeafNodeText), " "), r.RunTime
+			}
+			if r.State == "passed" || r.State == "skipped" || r.State == "pending" {
+				continue
+			}
+			for _, labels := range append(r.ContainerHierarchyLabels, r.LeafNodeLabels) {
+				for _, label := range labels {
+					failedLabels[label] = true
+				}
+			}
+		}
+	}
+
+	meta := map[string]string{"report-specs-retried": strconv.Itoa(retried)}
+	var counts []string
+	for state, n := range states {
+		meta["report-specs-"+state] = strconv.Itoa(n)
+		counts = append(counts, fmt.Sprintf("%d %s", n, state))
+	}
+	sort.Strings(counts)
+	if len(failedLabels) > 0 {
+		labels := make([]string, 0, len(failedLabels))
+		for label := range failedLabels {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		meta["report-failed-labels"] = strings.Join(labels, ",")
+	}
+	if !start.IsZero() && !end.IsZero() {
+		meta["report-start"] = start.UTC().Format(time.RFC3339)
+		meta["report-end"] = end.UTC().Format(time.RFC3339)
+		meta["report-duration"] = end.Sub(start).Round(time.Second).String()
+	}
+	if slowest != "" {
+		meta["report-slowest-spec"] = slowest
+		meta["report-slowest-spec-duration"] = slowestTime.Round(time.Millisecond).String()
+	}
+
+	klog.V(0).Infof("Ginkgo report %s: %s", path, strings.Join(counts, ", "))
+	return addMetadata(meta)
+}
@@ -0,0 +1,26 @@
+package tester
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// retry calls fn until it succeeds, retryable returns false for its error,
+// it was retried the given number of times or ctx is done. The interval
+// between attempts doubles after each failure.
+func retry(ctx context.Context, retries int, interval time.Duration, retryable func(error) bool, fn func() error) error {
+	err := fn()
+	for i := 0; i < retries && err != nil && ctx.Err() == nil && retryable(err); i++ {
+		klog.Warningf("attempt %d failed, retrying in %v: %v", i+1, interval, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		err = fn()
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+	always := func(error) bool { return true }
+	tests := []struct {
+		name      string
+		retries   int
+		failures  int
+		retryable func(error) bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", retries: 3, retryable: always, wantCalls: 1},
+		{name: "succeeds on retry", retries: 3, failures: 2, retryable: always, wantCalls: 3},
+		{name: "retries exhausted", retries: 2, failures: 5, retryable: always, wantCalls: 3, wantErr: true},
+		{
+			name:      "not retryable",
+			retries:   3,
+			failures:  5,
+			retryable: func(error) bool { return false },
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), tc.retries, time.Millisecond, tc.retryable, func() error {
+				calls++
+				if calls <= tc.failures {
+					return errTransient
+				}
+				return nil
+			})
+			if (err != nil) != tc.wantErr || calls != tc.wantCalls {
+				t.Errorf("retry() = %v after %d calls, want error %v after %d calls", err, calls, tc.wantErr, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	start := time.Now()
+	err := retry(ctx, 5, time.Hour, func(error) bool { return true }, func() error {
+		calls++
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return errors.New("transient")
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("retry() = %v after %d calls, want %v after 1 call", err, calls, context.Canceled)
+	}
+	if d := time.Since(start); d > time.Minute {
+		t.Errorf("retry() waited %v after the context was canceled", d)
+	}
+
+	calls = 0
+	err = retry(ctx, 5, time.Millisecond, func(error) bool { return true }, func() error {
+		calls++
+		return errors.New("transient")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("retry() = %v after %d calls with a done context, want an error after 1 call", err, calls)
+	}
+}
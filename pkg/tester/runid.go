@@ -0,0 +1,51 @@
+package tester
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// resolveRunID sets the run id to --run-id, else to the $KUBETEST2_RUN_ID
+// kubetest2 sets, else to a random UUID.
+func (t *Tester) resolveRunID() error {
+	if t.runID != "" {
+		return nil
+	}
+	t.runID = t.RunID
+	if t.runID == "" {
+		t.runID = os.Getenv("KUBETEST2_RUN_ID")
+	}
+	if t.runID == "" {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return fmt.Errorf("failed to generate run id: %v", err)
+		}
+		// version 4, variant 10
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		t.runID = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+	if strings.ContainsAny(t.runID, `/\`) {
+		return fmt.Errorf("invalid run id %q, it is used in file names", t.runID)
+	}
+	return nil
+}
+
+// artifactPath returns the path in the artifacts dir of the artifact name,
+// e.g. flakes.json. When --run-id is set, the run id is inserted before its
+// extension, e.g. flakes-<run id>.json, so that the artifacts of several
+// testers sharing the artifacts dir don't collide. The generated run ids are
+// only recorded in the metadata. The kubetest2 and conformance conventions,
+// metadata.json, e2e.log and the junit reports, always keep their names.
+func (t *Tester) artifactPath(name string) string {
+	if t.RunID == "" {
+		return filepath.Join(artifacts.BaseDir(), name)
+	}
+	ext := filepath.Ext(name)
+	return filepath.Join(artifacts.BaseDir(), strings.TrimSuffix(name, ext)+"-"+t.RunID+ext)
+}
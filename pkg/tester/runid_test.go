@@ -0,0 +1,48 @@
+package tester
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactPath(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	tests := []struct {
+		name  string
+		runID string
+		gen   string
+		in    string
+		want  string
+	}{
+		{
+			name: "generated run id",
+			gen:  "0f8c2e9a-uuid",
+			in:   "flakes.json",
+			want: "flakes.json",
+		},
+		{
+			name:  "explicit run id",
+			runID: "nightly-42",
+			gen:   "nightly-42",
+			in:    "flakes.json",
+			want:  "flakes-nightly-42.json",
+		},
+		{
+			name:  "explicit run id, no extension",
+			runID: "nightly-42",
+			gen:   "nightly-42",
+			in:    "git-version",
+			want:  "git-version-nightly-42",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tester := &Tester{runID: tc.gen}
+			tester.RunID = tc.runID
+			if got, want := tester.artifactPath(tc.in), filepath.Join(dir, tc.want); got != want {
+				t.Errorf("artifactPath(%q) = %q, want %q", tc.in, got, want)
+			}
+		})
+	}
+}
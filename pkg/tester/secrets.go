@@ -0,0 +1,167 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// resolveSecretEnv reads the secrets of the --env-from-secret entries, once.
+// A dry run doesn't read them and uses redacted values instead.
+func (t *Tester) resolveSecretEnv() ([]string, error) {
+	if t.secretEnv != nil || len(t.EnvFromSecret) == 0 {
+		return t.secretEnv, nil
+	}
+	secretEnv := make([]string, 0, len(t.EnvFromSecret))
+	for _, entry := range t.EnvFromSecret {
+		key, ref, ok := strings.Cut(entry, "=")
+		if !ok || key == "" || ref == "" {
+			return nil, fmt.Errorf("invalid --env-from-secret entry %q, must be KEY=SECRET", entry)
+		}
+		value := redacted
+		if scheme, _, _ := strings.Cut(ref, "://"); t.DryRun && scheme != "vault" && scheme != "gsm" && scheme != "awssm" {
+			return nil, fmt.Errorf("unsupported secret %q of %s, must be vault://, gsm:// or awssm://", ref, key)
+		}
+		if t.Offline {
+			return nil, fmt.Errorf("--offline forbids reading secret %s of %s, pass it with --env or --env-file", ref, key)
+		}
+		if !t.DryRun {
+			var err error
+			if value, err = readSecret(t.context(), ref); err != nil {
+				return nil, fmt.Errorf("failed to read secret of %s: %v", key, err)
+			}
+			klog.V(1).Infof("Read %s from %s", key, ref)
+		}
+		secretEnv = append(secretEnv, key+"="+value)
+	}
+	t.secretEnv = secretEnv
+	return t.secretEnv, nil
+}
+
+// readSecret returns the value of a vault://PATH#FIELD,
+// gsm://PROJECT/SECRET[/VERSION] or awssm://ARN[#FIELD] secret.
+func readSecret(ctx context.Context, ref string) (string, error) {
+	scheme, name, _ := strings.Cut(ref, "://")
+	switch scheme {
+	case "vault":
+		path, field, _ := strings.Cut(name, "#")
+		return readVaultSecret(ctx, path, field)
+	case "gsm":
+		parts := strings.Split(name, "/")
+		if len(parts) != 2 && len(parts) != 3 {
+			return "", fmt.Errorf("invalid secret %q, must be gsm://PROJECT/SECRET[/VERSION]", ref)
+		}
+		gcpName := "projects/" + parts[0] + "/secrets/" + parts[1]
+		if len(parts) == 3 {
+			gcpName += "/versions/" + parts[2]
+		}
+		return readGCPSecret(ctx, gcpName)
+	case "awssm":
+		id, field, _ := strings.Cut(name, "#")
+		return readAWSSecret(ctx, id, field)
+	}
+	return "", fmt.Errorf("unsupported secret %q, must be vault://PATH#FIELD, gsm://PROJECT/SECRET[/VERSION] or awssm://ARN[#FIELD]", ref)
+}
+
+// readAWSSecret returns the string value of an AWS Secrets Manager secret
+// with the aws CLI, or its field when the value is a JSON object.
+func readAWSSecret(ctx context.Context, id, field string) (string, error) {
+	cmd := exec.CommandContext(ctx, "aws", "secretsmanager", "get-secret-value",
+		"--secret-id="+id, "--query=SecretString", "--output=text")
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %v", id, err)
+	}
+	value := strings.TrimSpace(string(out))
+	if field == "" {
+		return value, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object to read field %q from", id, field)
+	}
+	v, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("no string field %q in secret %s", field, id)
+	}
+	return v, nil
+}
+
+// readGCPSecret returns the value of a Google Cloud Secret Manager secret,
+// projects/PROJECT/secrets/SECRET[/versions/VERSION], with gcloud.
+func readGCPSecret(ctx context.Context, name string) (string, error) {
+	parts := strings.Split(name, "/")
+	if (len(parts) != 4 && len(parts) != 6) || parts[0] != "projects" || parts[2] != "secrets" || (len(parts) == 6 && parts[4] != "versions") {
+		return "", fmt.Errorf("invalid secret %q, must be projects/PROJECT/secrets/SECRET[/versions/VERSION]", name)
+	}
+	version := "latest"
+	if len(parts) == 6 {
+		version = parts[5]
+	}
+	cmd := exec.CommandContext(ctx, "gcloud", "secrets", "versions", "access", version,
+		"--project="+parts[1], "--secret="+parts[3])
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s: %v", name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readVaultSecret returns field of the Vault secret at path, read with the
+// HTTP API of $VAULT_ADDR authenticated with $VAULT_TOKEN. Both KV v1 and v2
+// secrets are supported; with v2 the path includes the data/ segment, e.g.
+// secret/data/ci.
+func readVaultSecret(ctx context.Context, path, field string) (string, error) {
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("reading Vault secrets requires $VAULT_ADDR and $VAULT_TOKEN")
+	}
+	if field == "" {
+		return "", fmt.Errorf("invalid Vault secret %q, must be PATH#FIELD", path)
+	}
+
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Vault secret %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to read Vault secret %s: GET %s: %s", path, url, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse Vault secret %s: %v", path, err)
+	}
+	data := body.Data
+	// KV v2 nests the fields under data.data
+	if nested, ok := data["data"]; ok && data["metadata"] != nil {
+		data = nil
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", fmt.Errorf("failed to parse Vault secret %s: %v", path, err)
+		}
+	}
+	raw, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("no field %q in Vault secret %s", field, path)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("field %q of Vault secret %s is not a string", field, path)
+	}
+	return value, nil
+}
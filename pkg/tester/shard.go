@@ -0,0 +1,80 @@
+package tester
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// shardArgs returns the ginkgo arguments that restrict the run to the specs
+// of this shard, and records them in the artifacts dir. Specs are assigned
+// to shards by a hash of their name, so that adding or removing a spec does
+// not reshuffle the others. Each spec is focused by its exact name: specs of
+// a table share their location, so a location filter would run them in
+// every shard of the table.
+func (t *Tester) shardArgs() ([]string, error) {
+	specs, err := t.listSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	names := shardSpecs(specs, t.ShardIndex, t.ShardCount)
+	klog.V(0).Infof("Shard %d of %d runs %d of %d specs", t.ShardIndex, t.ShardCount, len(names), len(specs))
+
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	path := t.artifactPath(fmt.Sprintf("shard-%d-of-%d.txt", t.ShardIndex, t.ShardCount))
+	if err := os.WriteFile(path, []byte(strings.Join(names, "\n")+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write shard specs: %v", err)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("shard %d of %d has no specs to run", t.ShardIndex, t.ShardCount)
+	}
+	// one flag per spec, as a single regex of a large shard would exceed
+	// the size limit of an argument
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = "--focus=" + exactFocus(name)
+	}
+	return args, nil
+}
+
+// exactFocus returns the focus regex matching only the spec named name.
+func exactFocus(name string) string {
+	return "^" + regexp.QuoteMeta(name) + "$"
+}
+
+// shardSpecs returns the names of the specs assigned to shard index of
+// count, in the order of specs.
+func shardSpecs(specs []spec, index, count int) []string {
+	var names []string
+	for _, s := range specs {
+		h := fnv.New32a()
+		h.Write([]byte(s.Name))
+		if int(h.Sum32()%uint32(count)) == index {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+// withoutFocus returns args without the --ginkgo.focus of the test binary.
+// ginkgo runs the specs matching any of its focus flags, so the focus of the
+// run would select the specs of the other shards too; listSpecs() already
+// applied it.
+func withoutFocus(args []string) []string {
+	var filtered []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--ginkgo.focus=") {
+			filtered = append(filtered, arg)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,102 @@
+package tester
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShardSpecs(t *testing.T) {
+	var specs []spec
+	for i := 0; i < 50; i++ {
+		// specs of a table share their location
+		specs = append(specs, spec{Name: fmt.Sprintf("[sig-apps] Table entry %d [Slow]", i), File: "table.go", Line: 10})
+	}
+
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{name: "one shard", count: 1},
+		{name: "two shards", count: 2},
+		{name: "more shards than specs", count: 64},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := map[string]int{}
+			for index := 0; index < tc.count; index++ {
+				for _, name := range shardSpecs(specs, index, tc.count) {
+					seen[name]++
+				}
+			}
+			if len(seen) != len(specs) {
+				t.Errorf("shards run %d specs, want %d", len(seen), len(specs))
+			}
+			for name, n := range seen {
+				if n != 1 {
+					t.Errorf("%q is run by %d shards", name, n)
+				}
+			}
+		})
+	}
+}
+
+func TestShardSpecsStable(t *testing.T) {
+	specs := []spec{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	before := shardSpecs(specs, 0, 3)
+	after := shardSpecs(append(specs, spec{Name: "e"}), 0, 3)
+	for _, name := range before {
+		found := false
+		for _, other := range after {
+			found = found || other == name
+		}
+		if !found {
+			t.Errorf("adding a spec moved %q out of shard 0", name)
+		}
+	}
+}
+
+func TestExactFocus(t *testing.T) {
+	names := []string{
+		"[sig-node] Pods should run [Conformance]",
+		"[sig-node] Pods should run",
+		"[sig-node] Pods (v1) should run",
+	}
+	for _, name := range names {
+		focus := exactFocus(name)
+		re := regexp.MustCompile(focus)
+		for _, other := range names {
+			if got, want := re.MatchString(other), other == name; got != want {
+				t.Errorf("focus %q matches %q: %v, want %v", focus, other, got, want)
+			}
+		}
+	}
+}
+
+func TestWithoutFocus(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "drops the test binary focus",
+			args: []string{"--nodes=4", "e2e.test", "--", "--ginkgo.skip=Slow", "--ginkgo.focus=", "--kubeconfig=k"},
+			want: []string{"--nodes=4", "e2e.test", "--", "--ginkgo.skip=Slow", "--kubeconfig=k"},
+		},
+		{
+			name: "keeps the other args",
+			args: []string{"--focus=^a$", "e2e.test"},
+			want: []string{"--focus=^a$", "e2e.test"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := withoutFocus(tc.args); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("withoutFocus() = %s, want %s", strings.Join(got, " "), strings.Join(tc.want, " "))
+			}
+		})
+	}
+}
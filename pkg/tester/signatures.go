@@ -0,0 +1,116 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"k8s.io/klog"
+)
+
+// pgpKeyRingHeader starts the armored GPG public keyrings accepted by
+// --verify-signatures. Other files are read as SSH allowed signers files.
+const pgpKeyRingHeader = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// sshSignatureHeader starts the SSH signatures of commits.
+const sshSignatureHeader = "-----BEGIN SSH SIGNATURE-----"
+
+// verifySignatures checks that the cloned revision is signed by a key of
+// --verify-signatures. With --pr-merge, HEAD is the local merge commit and
+// its parents, the base branch and the pull request head, are checked
+// instead.
+func (t *Tester) verifySignatures(repo *git.Repository) error {
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	hashes := []plumbing.Hash{head.Hash()}
+	if t.PRMerge {
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to read commit %s: %v", head.Hash(), err)
+		}
+		hashes = commit.ParentHashes
+	}
+
+	var signedBy []string
+	for _, hash := range hashes {
+		signer, err := t.verifyCommit(repo, hash)
+		if err != nil {
+			return err
+		}
+		klog.V(0).Infof("Commit %s is signed by %s", hash, signer)
+		signedBy = append(signedBy, signer)
+	}
+	return addMetadata(map[string]string{"signed-by": strings.Join(signedBy, ", ")})
+}
+
+// verifyCommit checks that commit hash is signed by a key of
+// --verify-signatures and returns the signer.
+func (t *Tester) verifyCommit(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	keys, err := os.ReadFile(t.VerifySignatures)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --verify-signatures: %v", err)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %v", hash, err)
+	}
+	if commit.PGPSignature == "" {
+		return "", fmt.Errorf("commit %s is not signed", hash)
+	}
+
+	if strings.Contains(string(keys), pgpKeyRingHeader) {
+		if strings.HasPrefix(commit.PGPSignature, sshSignatureHeader) {
+			return "", fmt.Errorf("commit %s has an SSH signature, but --verify-signatures is a GPG keyring", hash)
+		}
+		entity, err := commit.Verify(string(keys))
+		if err != nil {
+			return "", fmt.Errorf("commit %s is not signed by a trusted key: %v", hash, err)
+		}
+		var identities []string
+		for name := range entity.Identities {
+			identities = append(identities, name)
+		}
+		sort.Strings(identities)
+		if len(identities) == 0 {
+			return entity.PrimaryKey.KeyIdString(), nil
+		}
+		return identities[0], nil
+	}
+	// git picks the verifier from the signature, so a GPG signature would
+	// be checked against the keyring of the runner instead
+	if !strings.HasPrefix(commit.PGPSignature, sshSignatureHeader) {
+		return "", fmt.Errorf("commit %s doesn't have an SSH signature, but --verify-signatures is an SSH allowed signers file", hash)
+	}
+	return t.verifySSHSignature(hash)
+}
+
+// verifySSHSignature checks the SSH signature of commit hash against the
+// allowed signers file of --verify-signatures with git verify-commit, as
+// go-git doesn't support SSH signatures.
+func (t *Tester) verifySSHSignature(hash plumbing.Hash) (string, error) {
+	if _, err := osexec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("verifying SSH signatures requires git: %v", err)
+	}
+	signers, err := filepath.Abs(t.VerifySignatures)
+	if err != nil {
+		return "", err
+	}
+	lines, err := t.git("-c", "gpg.format=ssh", "-c", "gpg.ssh.allowedSignersFile="+signers, "verify-commit", hash.String())
+	if err != nil {
+		return "", fmt.Errorf("commit %s is not signed by a trusted key: %v: %s", hash, err, strings.Join(lines, "\n"))
+	}
+	// e.g. Good "git" signature for alice@example.com with ED25519 key SHA256:...
+	for _, line := range lines {
+		if _, rest, ok := strings.Cut(line, " signature for "); ok {
+			return strings.TrimSpace(rest), nil
+		}
+	}
+	return strings.Join(lines, " "), nil
+}
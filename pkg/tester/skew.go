@@ -0,0 +1,120 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// maxMinorSkew is the number of minor versions the cluster may be away from
+// the tests.
+const maxMinorSkew = 1
+
+var (
+	minorVersionRe  = regexp.MustCompile(`^v?(\d+)\.(\d+)(\.\d+)?(-.*)?$`)
+	releaseBranchRe = regexp.MustCompile(`^release-(\d+)\.(\d+)$`)
+)
+
+// testVersion returns the kubernetes version of the tests, from the flags or
+// else the checked-out tree, empty when it cannot be told. The refs of repos
+// other than kubernetes follow their own versioning.
+func (t *Tester) testVersion() string {
+	if t.TestPackageVersion != "" {
+		if minorVersionRe.MatchString(t.TestPackageVersion) {
+			return t.TestPackageVersion
+		}
+		return ""
+	}
+	if !t.isKubernetesTree() {
+		return ""
+	}
+	switch {
+	case minorVersionRe.MatchString(t.Tag):
+		return t.Tag
+	case t.Tag == "" && releaseBranchRe.MatchString(t.Branch):
+		return t.Branch
+	}
+	return t.treeVersion()
+}
+
+// isKubernetesTree reports whether the tests are those of the kubernetes
+// repo, by the --repo name or, e.g. with --skip-clone, by its version script.
+func (t *Tester) isKubernetesTree() bool {
+	if repoName(t.Repo) == "kubernetes" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(t.sourceDir(), "hack", "lib", "version.sh"))
+	return err == nil
+}
+
+// treeVersion returns the version of the checked-out tree described by its
+// closest release tag, as hack/lib/version.sh does, e.g.
+// v1.30.0-alpha.0.123-gabcdef, empty when it has none, e.g. in a shallow
+// clone.
+func (t *Tester) treeVersion() string {
+	cmd := exec.CommandContext(t.context(), "git", "describe", "--tags", "--match=v*", "--abbrev=14", "HEAD^{commit}")
+	cmd.SetDir(t.sourceDir())
+	lines, err := exec.OutputLines(cmd)
+	if err != nil || len(lines) == 0 {
+		klog.V(1).Infof("Failed to describe the version of %s: %v", t.sourceDir(), err)
+		return ""
+	}
+	if !minorVersionRe.MatchString(lines[0]) {
+		klog.V(1).Infof("Ignoring version %s of %s, it is not a release version", lines[0], t.sourceDir())
+		return ""
+	}
+	return lines[0]
+}
+
+// checkVersionSkew fails when the minor versions of the cluster and the
+// tests are further apart than maxMinorSkew, since most specs would fail.
+func (t *Tester) checkVersionSkew() error {
+	testVersion := t.testVersion()
+	if testVersion == "" {
+		klog.V(0).Infof("Unknown version of the tests, neither --test-package-version, a release --tag or --branch nor a release tag of the checked-out tree gives it, not checking the cluster version skew")
+		return nil
+	}
+	serverVersion, err := t.serverVersion()
+	if err != nil {
+		klog.Warningf("failed to get the cluster version, not checking the version skew: %v", err)
+		return nil
+	}
+
+	testMajor, testMinor := parseMinorVersion(testVersion)
+	serverMajor, serverMinor := parseMinorVersion(serverVersion)
+	skew := testMinor - serverMinor
+	if skew < 0 {
+		skew = -skew
+	}
+	if testMajor == serverMajor && skew <= maxMinorSkew {
+		klog.V(1).Infof("Testing cluster version %s with tests of version %s", serverVersion, testVersion)
+		return nil
+	}
+
+	err = fmt.Errorf("cluster version %s is more than %d minor version away from the tests version %s", serverVersion, maxMinorSkew, testVersion)
+	if t.AllowSkew {
+		klog.Warningf("%v, continuing because of --allow-skew", err)
+		return nil
+	}
+	return fmt.Errorf("%v, use --allow-skew to run anyway", err)
+}
+
+// parseMinorVersion returns the major and minor version of a version
+// matched by minorVersionRe or releaseBranchRe.
+func parseMinorVersion(version string) (major, minor int) {
+	m := minorVersionRe.FindStringSubmatch(version)
+	if m == nil {
+		m = releaseBranchRe.FindStringSubmatch(version)
+	}
+	if m == nil {
+		return 0, 0
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	return major, minor
+}
@@ -0,0 +1,74 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestTestVersionFromTree(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+		if _, err := wt.Commit(name, &git.CommitOptions{Author: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tester := &Tester{}
+	tester.Repo = "https://github.com/kubernetes/kubernetes"
+	tester.CheckoutDir = dir
+
+	commit("a")
+	if got := tester.testVersion(); got != "" {
+		t.Errorf("testVersion() = %q without release tags, want none", got)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	if _, err := repo.CreateTag("v1.29.0", head.Hash(), &git.CreateTagOptions{Tagger: sig, Message: "v1.29.0"}); err != nil {
+		t.Fatal(err)
+	}
+	commit("b")
+	got := tester.testVersion()
+	if !regexp.MustCompile(`^v1\.29\.0-1-g[0-9a-f]{14}$`).MatchString(got) {
+		t.Errorf("testVersion() = %q, want v1.29.0-1-g<commit>", got)
+	}
+	if major, minor := parseMinorVersion(got); major != 1 || minor != 29 {
+		t.Errorf("parseMinorVersion(%q) = %d.%d, want 1.29", got, major, minor)
+	}
+
+	tester.Tag = "v1.28.3"
+	if got := tester.testVersion(); got != "v1.28.3" {
+		t.Errorf("testVersion() = %q with --tag, want v1.28.3", got)
+	}
+
+	tester.Tag = ""
+	tester.Repo = "https://github.com/org/operator"
+	if got := tester.testVersion(); got != "" {
+		t.Errorf("testVersion() = %q for another repo, want none", got)
+	}
+}
@@ -0,0 +1,105 @@
+package tester
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog"
+)
+
+// skipListPatterns returns the patterns of the --skip-url skip lists. Each
+// list fetched is cached, and the cached copy is used when the fetch fails
+// or with --offline.
+func (t *Tester) skipListPatterns() ([]string, error) {
+	var patterns []string
+	for _, u := range t.SkipURL {
+		if _, ok := t.skipLists[u]; !ok {
+			list, err := t.fetchSkipList(u)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --skip-url: %v", err)
+			}
+			if t.skipLists == nil {
+				t.skipLists = map[string][]string{}
+			}
+			t.skipLists[u] = list
+		}
+		patterns = append(patterns, t.skipLists[u]...)
+	}
+	return patterns, nil
+}
+
+// fetchSkipList downloads the skip list at u into the cache, unless
+// --offline or --dry-run, and returns the patterns of the cached copy.
+func (t *Tester) fetchSkipList(u string) ([]string, error) {
+	path, err := t.skipListCachePath(u)
+	if err != nil {
+		return nil, err
+	}
+	_, statErr := os.Stat(path)
+	cached := statErr == nil
+
+	switch {
+	case t.Offline && !cached:
+		return nil, offlineError("skip list "+t.redact(u), "a copy cached in "+path+" by a previous run, or --skip-file")
+	case t.DryRun && !cached:
+		return nil, fmt.Errorf("skip list %s is not cached, and the dry run doesn't fetch it", t.redact(u))
+	case t.Offline || t.DryRun:
+		klog.V(0).Infof("Using the cached skip list of %s from %s", t.redact(u), path)
+	default:
+		// the cache is only replaced by a valid list
+		download := path + ".new"
+		err := downloadFile(t.context(), u, download, 0644)
+		if err == nil {
+			if _, err = readPatternFile(download); err == nil {
+				err = os.Rename(download, path)
+			}
+		}
+		os.Remove(download)
+		if err != nil && !cached {
+			return nil, fmt.Errorf("failed to fetch skip list %s: %v", t.redact(u), t.redact(err.Error()))
+		}
+		if err != nil {
+			klog.Warningf("failed to fetch skip list %s, using the copy cached in %s: %v", t.redact(u), path, t.redact(err.Error()))
+		}
+	}
+
+	patterns, err := readPatternFile(path)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(1).Infof("Read %d patterns from skip list %s", len(patterns), t.redact(u))
+	return patterns, nil
+}
+
+// skipListCachePath returns where the skip list at u is cached: in the
+// --cache-dir when set, else in the user cache dir.
+func (t *Tester) skipListCachePath(u string) (string, error) {
+	dir := t.CacheDir
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user cache directory: %v", err)
+		}
+		dir = filepath.Join(userCache, "kubetest2-tester-gitremote")
+	}
+	dir = filepath.Join(dir, "skip-lists")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])[:12]+".txt"), nil
+}
+
+// validateSkipURLs checks the --skip-url are http(s) URLs.
+func (t *Tester) validateSkipURLs() error {
+	for _, s := range t.SkipURL {
+		if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid --skip-url %q, must be an http or https URL", t.redact(s))
+		}
+	}
+	return nil
+}
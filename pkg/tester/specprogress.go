@@ -0,0 +1,227 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+var (
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	// willRun is printed by ginkgo before running the specs
+	willRun = regexp.MustCompile(`Will run (\d+) of \d+ specs`)
+	// succinctResults are the results of specs without output, e.g. •••S•
+	succinctResults = regexp.MustCompile(`^[•SP]+$`)
+	// resultHeader starts the report of a spec with output, e.g.
+	// "• [FAILED] [0.002 seconds]" or "S [SKIPPED] [0.000 seconds]"
+	resultHeader = regexp.MustCompile(`^([•SP]) (\[|Failure|Panic)`)
+	// specEnter is printed with --show-node-events when a spec starts
+	specEnter = regexp.MustCompile(`^> Enter \[It\] (.*) - \S+:\d+ @`)
+	// codeLocation is a file:line line of a spec report
+	codeLocation = regexp.MustCompile(`^\S+:\d+$`)
+	// failureDetails start the failure message of a spec report, e.g.
+	// "[FAILED] Expected..."
+	failureDetails = regexp.MustCompile(`^\[[A-Z]+\]`)
+)
+
+// specProgress parses the streamed ginkgo output to log the result of every
+// spec as it completes, and keeps progress.json in the artifacts dir up to
+// date for dashboards to follow long suites.
+type specProgress struct {
+	path string
+
+	mu  sync.Mutex
+	buf []byte
+	// result is the result of a spec whose name is being read from the
+	// lines following its header
+	result    string
+	nameLines []string
+	report    progressReport
+}
+
+// progressReport is the content of progress.json.
+type progressReport struct {
+	// Total is the number of specs ginkgo will run, or 0 until it's known
+	Total     int       `json:"total"`
+	Completed int       `json:"completed"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Flaked    int       `json:"flaked"`
+	Skipped   int       `json:"skipped"`
+	Current   string    `json:"current,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// newSpecProgress returns the progress of the spec results written to it,
+// nil with --spec-progress disabled.
+func (t *Tester) newSpecProgress() *specProgress {
+	if !t.SpecProgress {
+		return nil
+	}
+	p := &specProgress{path: t.artifactPath("progress.json")}
+	p.write()
+	return p
+}
+
+// Write parses the complete lines of output.
+func (p *specProgress) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		p.parse(string(p.buf[:i]))
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// Close parses the output left and writes the final progress.
+func (p *specProgress) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		p.parse(string(p.buf))
+		p.buf = nil
+	}
+	if p.result != "" {
+		p.complete(p.result, p.specName())
+	}
+	p.report.Current = ""
+	return p.write()
+}
+
+func (p *specProgress) parse(line string) {
+	line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+
+	if p.result != "" {
+		switch {
+		case line == "" || strings.HasPrefix(line, "---") || failureDetails.MatchString(line):
+			p.complete(p.result, p.specName())
+		case codeLocation.MatchString(line):
+		case strings.HasSuffix(line, "[It]"):
+			p.nameLines = append(p.nameLines, strings.TrimSpace(strings.TrimSuffix(line, "[It]")))
+			p.complete(p.result, p.specName())
+		default:
+			p.nameLines = append(p.nameLines, line)
+		}
+		return
+	}
+
+	if m := willRun.FindStringSubmatch(line); m != nil {
+		p.report.Total, _ = strconv.Atoi(m[1])
+		p.write()
+		return
+	}
+	if m := specEnter.FindStringSubmatch(line); m != nil {
+		klog.V(0).Infof("Spec started: %s", m[1])
+		p.report.Current = m[1]
+		p.write()
+		return
+	}
+	if succinctResults.MatchString(line) {
+		for _, r := range line {
+			p.complete(succinctResult(r), "")
+		}
+		return
+	}
+	if m := resultHeader.FindStringSubmatch(line); m != nil {
+		p.result = headerResult(m[1], line)
+		p.nameLines = nil
+	}
+}
+
+// succinctResult returns the result of a spec printed as a single rune.
+func succinctResult(r rune) string {
+	if r == '•' {
+		return "passed"
+	}
+	return "skipped"
+}
+
+// headerResult returns the result of the spec reported under header,
+// starting with marker.
+func headerResult(marker, header string) string {
+	switch {
+	case marker != "•":
+		return "skipped"
+	case strings.Contains(header, "FLAKEY"):
+		return "flaked"
+	}
+	for _, failure := range []string{"FAILED", "Failure", "PANICKED", "Panic", "TIMEDOUT", "INTERRUPTED", "ABORTED"} {
+		if strings.Contains(header, failure) {
+			return "failed"
+		}
+	}
+	return "passed"
+}
+
+func (p *specProgress) specName() string {
+	return strings.Join(p.nameLines, " ")
+}
+
+// complete records the result of the spec name, empty when not printed.
+func (p *specProgress) complete(result, name string) {
+	p.result, p.nameLines = "", nil
+	r := &p.report
+	switch result {
+	case "skipped":
+		r.Skipped++
+		if name != "" {
+			klog.V(2).Infof("Spec skipped: %s", name)
+		}
+		p.write()
+		return
+	case "passed":
+		r.Passed++
+	case "flaked":
+		r.Flaked++
+	case "failed":
+		r.Failed++
+	}
+	r.Completed++
+	// the spec start only prints the leaf text
+	if r.Current != "" && strings.HasSuffix(name, r.Current) {
+		r.Current = ""
+	}
+
+	count := strconv.Itoa(r.Completed)
+	if r.Total > 0 {
+		count += "/" + strconv.Itoa(r.Total)
+	}
+	if name == "" {
+		klog.V(0).Infof("Spec %s (%s)", result, count)
+	} else {
+		klog.V(0).Infof("Spec %s (%s): %s", result, count, name)
+	}
+	p.write()
+}
+
+// write replaces progress.json, so that readers never see a partial file.
+func (p *specProgress) write() error {
+	p.report.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(p.report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write spec progress: %v", err)
+	}
+	return os.Rename(tmp, p.path)
+}
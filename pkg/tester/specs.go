@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// spec is a ginkgo spec selected by the focus, skip and label filters.
+type spec struct {
+	Name string `json:"name"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// location returns the file:line ginkgo location filter matching s.
+func (s spec) location() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// ginkgoReport is the subset of the ginkgo v2 JSON report needed to list
+// specs, detect flakes and summarize runs.
+type ginkgoReport []struct {
+	StartTime   time.Time
+	EndTime     time.Time
+	SpecReports []struct {
+		ContainerHierarchyTexts  []string
+		ContainerHierarchyLabels [][]string
+		LeafNodeText             string
+		LeafNodeType             string
+		LeafNodeLabels           []string
+		LeafNodeLocation         struct {
+			FileName   string
+			LineNumber int
+		}
+		State       string
+		NumAttempts int
+		RunTime     time.Duration
+	}
+}
+
+// listSpecs enumerates the specs selected by the focus, skip and label
+// filters with a ginkgo dry run, sorted by name. It needs ginkgo v2.
+func (t *Tester) listSpecs() ([]spec, error) {
+	if v := t.ginkgoMajorVersion(); v == "1" {
+		return nil, fmt.Errorf("listing specs requires ginkgo v2")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "ginkgo-dry-run")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	args := []string{"--dry-run", "--output-dir=" + tmpDir, "--json-report=report.json"}
+	if t.FocusRegex != "" {
+		args = append(args, "--focus="+t.FocusRegex)
+	}
+	if t.SkipRegex != "" {
+		args = append(args, "--skip="+t.SkipRegex)
+	}
+	if t.LabelFilter != "" {
+		args = append(args, "--label-filter="+t.LabelFilter)
+	}
+	args = append(args, t.e2eTestPath, "--", "--kubeconfig="+t.kubeconfigPath)
+
+	klog.V(1).Infof("Listing specs with %s %+v", t.ginkgoPath, t.redactAll(args))
+	cmd := exec.CommandContext(t.context(), t.ginkgoPath, args...)
+	cmd.SetEnv(t.env...)
+	exec.NoOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ginkgo dry run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "report.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ginkgo dry run report: %v", err)
+	}
+	var report ginkgoReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse ginkgo dry run report: %v", err)
+	}
+
+	var specs []spec
+	for _, suite := range report {
+		for _, r := range suite.SpecReports {
+			// specs excluded by the filters are reported as skipped
+			if r.LeafNodeType != "It" || r.State != "passed" {
+				continue
+			}
+			specs = append(specs, spec{
+				Name: strings.Join(append(r.ContainerHierarchyTexts, r.LeafNodeText), " "),
+				File: r.LeafNodeLocation.FileName,
+				Line: r.LeafNodeLocation.LineNumber,
+			})
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs, nil
+}
+
+// printSpecs writes the names of the selected specs to w, and the specs with
+// their locations to specs.json in the artifacts dir.
+func (t *Tester) printSpecs(w io.Writer) error {
+	specs, err := t.listSpecs()
+	if err != nil {
+		return err
+	}
+	for _, s := range specs {
+		fmt.Fprintln(w, s.Name)
+	}
+
+	if specs == nil {
+		specs = []spec{}
+	}
+	data, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(artifacts.BaseDir(), os.ModePerm); err != nil {
+		return err
+	}
+	path := t.artifactPath("specs.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	klog.V(0).Infof("Listed %d specs to %s", len(specs), path)
+	return nil
+}
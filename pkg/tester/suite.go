@@ -0,0 +1,231 @@
+package tester
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/yaml"
+)
+
+// suitePass is one of the suites run back to back by runSuites().
+type suitePass struct {
+	// name is appended to the junit report prefix and the e2e.log name
+	name string
+	// load applies the settings of the pass
+	load func() error
+}
+
+// suitePasses returns the passes of several --suite or of --auto-split,
+// none when the suite runs once.
+func (t *Tester) suitePasses() []suitePass {
+	if t.AutoSplit {
+		return t.autoSplitPasses()
+	}
+	if len(t.Suite) < 2 {
+		return nil
+	}
+	var passes []suitePass
+	for _, name := range t.Suite {
+		name := name
+		passes = append(passes, suitePass{name: name, load: func() error {
+			if err := t.loadSuite(name); err != nil {
+				return err
+			}
+			return t.loadPatternFiles()
+		}})
+	}
+	return passes
+}
+
+// runSuites runs the suite with run, or each of its passes in turn with
+// their own settings and junit report prefix. All the passes run even when
+// one fails, unless the tester times out.
+func (t *Tester) runSuites(run func() error) error {
+	passes := t.suitePasses()
+	if len(passes) == 0 {
+		return t.runSuite(run)
+	}
+
+	// every pass is loaded from the state left by the setup
+	options, env := t.Options, t.env
+	defer func() { t.Options, t.env, t.suitePass = options, env, "" }()
+
+	var failed []string
+	var firstErr error
+	for i, pass := range passes {
+		t.Options, t.env, t.rerunNothing = options, env, false
+		t.JUnitReportPrefix += pass.name + "_"
+		t.suitePass = pass.name
+		if err := pass.load(); errors.Is(err, errNoSpecs) {
+			klog.V(0).Infof("Suite %q has no specs to run, skipping it", pass.name)
+			continue
+		} else if err != nil {
+			return classify(failureInfra, err)
+		}
+		if t.rerunNothing {
+			klog.V(0).Infof("No failed specs of suite %q in %s, skipping it", pass.name, t.RerunFailedFrom)
+			continue
+		}
+
+		klog.V(0).Infof("Running suite %q (%d of %d)", pass.name, i+1, len(passes))
+		err := t.runSuite(run)
+		if renameErr := renameTestLog(pass.name); renameErr != nil {
+			klog.Warning(renameErr)
+		}
+		if err == nil {
+			continue
+		}
+		klog.Warningf("suite %q failed: %v", pass.name, err)
+		failed = append(failed, pass.name)
+		if firstErr == nil {
+			firstErr = err
+		}
+		if errors.Is(err, errTesterTimeout) {
+			break
+		}
+	}
+	if firstErr != nil {
+		return fmt.Errorf("suites %v failed, first with: %w", failed, firstErr)
+	}
+	return nil
+}
+
+// renameTestLog renames the e2e.log of a suite that ran with others to
+// e2e_<suite>.log, so that the next suite doesn't overwrite it.
+func renameTestLog(suite string) error {
+	path := filepath.Join(artifacts.BaseDir(), "e2e.log")
+	if err := os.Rename(path, filepath.Join(artifacts.BaseDir(), "e2e_"+suite+".log")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rename the test log of suite %q: %v", suite, err)
+	}
+	return nil
+}
+
+// suitesPath returns the path of the suites file.
+func (t *Tester) suitesPath() string {
+	if filepath.IsAbs(t.SuitesFile) {
+		return t.SuitesFile
+	}
+	return filepath.Join(t.sourceDir(), t.SuitesFile)
+}
+
+// checkSuites checks that every --suite is defined in the suites file.
+func (t *Tester) checkSuites() error {
+	path := t.suitesPath()
+	suites, err := readSuites(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range t.Suite {
+		if _, ok := suites[name]; !ok {
+			return undefinedSuiteError(path, name, suites)
+		}
+	}
+	return nil
+}
+
+// undefinedSuiteError is the error of a --suite that isn't in suites.
+func undefinedSuiteError(path, suite string, suites map[string]suiteSettings) error {
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("suite %q is not defined in %s, defined suites: %v", suite, path, names)
+}
+
+// loadSuite applies the settings of the suite preset defined in the suites
+// file of the cloned repo. Settings only fill in the test selection flags
+// left at their defaults, and --env entries take precedence over the suite
+// env.
+func (t *Tester) loadSuite(suite string) error {
+	path := t.suitesPath()
+	suites, err := readSuites(path)
+	if err != nil {
+		return err
+	}
+	settings, ok := suites[suite]
+	if !ok {
+		return undefinedSuiteError(path, suite, suites)
+	}
+
+	klog.V(0).Infof("Using suite %q from %s", suite, path)
+	setDefault(&t.FocusRegex, settings.FocusRegex)
+	setDefault(&t.SkipRegex, settings.SkipRegex)
+	setDefault(&t.LabelFilter, settings.LabelFilter)
+	setDefault(&t.GinkgoArgs, settings.GinkgoArgs)
+	if err := setDefaultCount(&t.Parallel, settings.Parallel); err != nil {
+		return fmt.Errorf("%s: suite %q: invalid parallel: %v", path, suite, err)
+	}
+	if err := setDefaultCount(&t.FlakeAttempts, settings.FlakeAttempts); err != nil {
+		return fmt.Errorf("%s: suite %q: invalid flake-attempts: %v", path, suite, err)
+	}
+
+	if len(settings.Env) > 0 {
+		t.Env = append(append([]string(nil), settings.Env...), t.Env...)
+		// keep the changes made to the env since it was resolved, e.g. by
+		// installGo()
+		if _, err := t.resolveEnv(); err != nil {
+			return err
+		}
+		t.env = mergeEnv(t.env, t.envOverrides)
+	}
+	return nil
+}
+
+// setDefault sets *field to value unless it was set by a flag.
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// setDefaultCount sets *field to value, if any, unless it was set by a flag.
+func setDefaultCount(field *int, value *int) error {
+	if value == nil {
+		return nil
+	}
+	if *value < 1 {
+		return fmt.Errorf("%d is less than 1", *value)
+	}
+	if *field == 1 {
+		*field = *value
+	}
+	return nil
+}
+
+// suiteSettings are the settings of a suite of the suites file, named after
+// the flags they fill in.
+type suiteSettings struct {
+	FocusRegex    string   `json:"focus-regex"`
+	SkipRegex     string   `json:"skip-regex"`
+	LabelFilter   string   `json:"label-filter"`
+	GinkgoArgs    string   `json:"ginkgo-args"`
+	Parallel      *int     `json:"parallel"`
+	FlakeAttempts *int     `json:"flake-attempts"`
+	Env           []string `json:"env"`
+}
+
+// readSuites reads a suites file mapping suite names to their settings, in
+// YAML or JSON, e.g.
+//
+//	serial:
+//	  focus-regex: \[Serial\]
+//	  env:
+//	    - KUBE_TEST_REPO_LIST=repos.yaml
+func readSuites(path string) (map[string]suiteSettings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suites file: %v", err)
+	}
+	var suites map[string]suiteSettings
+	// JSON is parsed as YAML too, and unknown settings are rejected
+	if err := yaml.UnmarshalStrict(data, &suites); err != nil {
+		return nil, fmt.Errorf("failed to parse suites file %s: %v", path, err)
+	}
+	return suites, nil
+}
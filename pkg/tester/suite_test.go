@@ -0,0 +1,153 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestReadSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    map[string]suiteSettings
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			file: "suites.yaml",
+			data: `# presets
+serial:
+  focus-regex: \[Serial\]
+  parallel: 1
+  env:
+    - KUBE_TEST_REPO_LIST=repos.yaml
+parallel:
+  skip-regex: '\[Serial\]|\[Slow\]'
+  label-filter: "!Flaky"
+  parallel: 8
+  flake-attempts: 2
+empty:
+`,
+			want: map[string]suiteSettings{
+				"serial":   {FocusRegex: `\[Serial\]`, Parallel: intPtr(1), Env: []string{"KUBE_TEST_REPO_LIST=repos.yaml"}},
+				"parallel": {SkipRegex: `\[Serial\]|\[Slow\]`, LabelFilter: "!Flaky", Parallel: intPtr(8), FlakeAttempts: intPtr(2)},
+				"empty":    {},
+			},
+		},
+		{
+			name: "json",
+			file: "suites.json",
+			data: `{"conformance": {"focus-regex": "\\[Conformance\\]", "ginkgo-args": "--timeout=2h"}}`,
+			want: map[string]suiteSettings{
+				"conformance": {FocusRegex: `\[Conformance\]`, GinkgoArgs: "--timeout=2h"},
+			},
+		},
+		{
+			name:    "unknown setting",
+			file:    "suites.yaml",
+			data:    "serial:\n  focus: Serial\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid parallel",
+			file:    "suites.yaml",
+			data:    "serial:\n  parallel: many\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a mapping",
+			file:    "suites.yaml",
+			data:    "- serial\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readSuites(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("readSuites() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readSuites() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("readSuites() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadSuite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suites.yaml")
+	data := `serial:
+  focus-regex: \[Serial\]
+  skip-regex: Flaky
+  parallel: 4
+  flake-attempts: 0
+parallel:
+  parallel: 8
+  flake-attempts: 3
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		suite   string
+		options Options
+		want    Options
+		wantErr bool
+	}{
+		{
+			name:    "flags win",
+			suite:   "parallel",
+			options: Options{Parallel: 2, FlakeAttempts: 1, FocusRegex: "Foo"},
+			want:    Options{Parallel: 2, FlakeAttempts: 3, FocusRegex: "Foo"},
+		},
+		{
+			name:    "invalid count",
+			suite:   "serial",
+			options: Options{Parallel: 1, FlakeAttempts: 1},
+			wantErr: true,
+		},
+		{
+			name:    "undefined suite",
+			suite:   "missing",
+			options: Options{Parallel: 1, FlakeAttempts: 1},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.options.SuitesFile = path
+			tester := &Tester{Options: tc.options}
+			err := tester.loadSuite(tc.suite)
+			if tc.wantErr {
+				if err == nil {
+					t.Error("loadSuite() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadSuite() failed: %v", err)
+			}
+			tc.want.SuitesFile = path
+			if !reflect.DeepEqual(tester.Options, tc.want) {
+				t.Errorf("loadSuite() set %+v, want %+v", tester.Options, tc.want)
+			}
+		})
+	}
+}
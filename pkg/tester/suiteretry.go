@@ -0,0 +1,105 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// runSuite calls run, rerunning it up to --suite-retries times while it
+// fails with the cluster unhealthy. The reports of the failed attempts are
+// moved to $ARTIFACTS/suite-attempt-<n>. Every attempt gets the full
+// --timeout.
+func (t *Tester) runSuite(run func() error) error {
+	attempt := 0
+	err := retry(t.context(), t.SuiteRetries, t.SuiteRetryInterval, t.clusterFailure, func() error {
+		attempt++
+		if attempt > 1 {
+			if err := t.archiveAttempt(attempt - 1); err != nil {
+				return err
+			}
+			// wait for the cluster to recover before starting over
+			if err := t.checkCluster(); err != nil {
+				return classify(failureInfra, err)
+			}
+			klog.V(0).Infof("Rerunning the suite, attempt %d of %d", attempt, t.SuiteRetries+1)
+		}
+		return run()
+	})
+	if t.SuiteRetries > 0 {
+		if metaErr := addMetadata(map[string]string{"suite-attempts": strconv.Itoa(attempt)}); metaErr != nil {
+			klog.Warningf("failed to write suite attempts to metadata: %v", metaErr)
+		}
+	}
+	return err
+}
+
+// clusterFailure reports whether the suite failing with err is blamed on
+// the cluster, in which case it is worth rerunning.
+func (t *Tester) clusterFailure(err error) bool {
+	if errors.Is(err, errTesterTimeout) {
+		return false
+	}
+	// the cluster did not recover in time for the last attempt
+	var classified *classifiedError
+	if errors.As(err, &classified) {
+		return classified.class == failureInfra
+	}
+	if healthErr := t.clusterHealth(); healthErr != nil {
+		klog.Warningf("the suite failed with the cluster unhealthy: %v", healthErr)
+		return true
+	}
+	return false
+}
+
+// clusterHealth probes the cluster once, failing if the API server is
+// unreachable, any node is NotReady or fewer than --wait-for-nodes are Ready.
+func (t *Tester) clusterHealth() error {
+	ctx, cancel := context.WithTimeout(t.context(), t.PreflightTimeout)
+	defer cancel()
+
+	if _, err := t.kubectlOutput(ctx, "get", "--raw", "/version"); err != nil {
+		return fmt.Errorf("API server is not reachable: %v", err)
+	}
+	ready, total, err := t.readyNodes(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %v", err)
+	}
+	if ready < total {
+		return fmt.Errorf("%d of %d nodes are NotReady", total-ready, total)
+	}
+	if ready < t.WaitForNodes {
+		return fmt.Errorf("%d of the %d required nodes are Ready", ready, t.WaitForNodes)
+	}
+	return nil
+}
+
+// archiveAttempt moves the reports of a failed suite attempt out of the way
+// of the next one.
+func (t *Tester) archiveAttempt(attempt int) error {
+	name := "suite-attempt-" + strconv.Itoa(attempt)
+	if t.suitePass != "" {
+		name = t.suitePass + "_" + name
+	}
+	dir := filepath.Join(artifacts.BaseDir(), name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	// only the reports of the current suite, when several run
+	reports, err := filepath.Glob(filepath.Join(artifacts.BaseDir(), "junit_"+t.JUnitReportPrefix+"*.xml"))
+	if err != nil {
+		return err
+	}
+	for _, path := range append(reports, filepath.Join(artifacts.BaseDir(), "e2e.log"), t.ginkgoJSONReportPath()) {
+		if err := os.Rename(path, filepath.Join(dir, filepath.Base(path))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to archive suite attempt %d: %v", attempt, err)
+		}
+	}
+	return nil
+}
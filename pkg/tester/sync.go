@@ -0,0 +1,151 @@
+package tester
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// The --sync-strategy values, what is done with an existing clone in the
+// checkout dir.
+const (
+	// syncStrategyReset fetches and hard resets the worktree, keeping the
+	// ignored files such as previous build outputs.
+	syncStrategyReset = "reset"
+	// syncStrategyClean resets and removes the untracked and ignored files,
+	// like git clean -xfd.
+	syncStrategyClean = "clean"
+	// syncStrategyRebase fetches and rebases the local commits and changes
+	// onto the fetched revision.
+	syncStrategyRebase = "rebase"
+	// syncStrategyFresh removes the checkout dir and clones again.
+	syncStrategyFresh = "fresh"
+)
+
+// validateSyncStrategy checks --sync-strategy.
+func (t *Tester) validateSyncStrategy() error {
+	switch t.SyncStrategy {
+	case syncStrategyReset, syncStrategyClean, syncStrategyFresh:
+	case syncStrategyRebase:
+		if len(t.SparsePaths) > 0 {
+			return fmt.Errorf("--sync-strategy=%s can't be used with --sparse-paths", syncStrategyRebase)
+		}
+	default:
+		return fmt.Errorf("unsupported --sync-strategy %q, must be one of %q, %q, %q or %q", t.SyncStrategy, syncStrategyReset, syncStrategyClean, syncStrategyRebase, syncStrategyFresh)
+	}
+	return nil
+}
+
+// syncWorktree updates the worktree of an existing clone to hash following
+// --sync-strategy.
+func (t *Tester) syncWorktree(repo *git.Repository, ref plumbing.ReferenceName, hash plumbing.Hash, sparse []string) error {
+	if t.SyncStrategy == syncStrategyRebase {
+		return t.rebaseWorktree(repo, ref, hash)
+	}
+
+	klog.V(0).Infof("Resetting to %s (%s)", ref, hash)
+	if err := checkout(repo, &git.CheckoutOptions{Hash: hash, Force: true}, sparse); err != nil {
+		return fmt.Errorf("failed to reset to %s: %v", ref, err)
+	}
+	if t.SyncStrategy == syncStrategyClean {
+		return cleanWorktree(repo)
+	}
+	return nil
+}
+
+// rebaseWorktree rebases the local commits and uncommitted changes of the
+// worktree onto hash with the git CLI, as go-git can't rebase. A conflicting
+// rebase is aborted.
+func (t *Tester) rebaseWorktree(repo *git.Repository, ref plumbing.ReferenceName, hash plumbing.Hash) error {
+	if _, err := osexec.LookPath("git"); err != nil {
+		return fmt.Errorf("--sync-strategy=%s requires git: %v", syncStrategyRebase, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %v", err)
+	}
+	dir := wt.Filesystem.Root()
+
+	klog.V(0).Infof("Rebasing the local changes in %s onto %s (%s)", dir, ref, hash)
+	cmd := exec.CommandContext(t.context(), "git", "-c", "user.name=kubetest2", "-c", "user.email=kubetest2@localhost",
+		"rebase", "--autostash", hash.String())
+	cmd.SetDir(dir)
+	lines, err := exec.CombinedOutputLines(cmd)
+	if err == nil {
+		return nil
+	}
+	abort := exec.CommandContext(t.context(), "git", "rebase", "--abort")
+	abort.SetDir(dir)
+	if abortErr := abort.Run(); abortErr != nil {
+		klog.Warningf("failed to abort the rebase in %s: %v", dir, abortErr)
+	}
+	return fmt.Errorf("failed to rebase the local changes in %s onto %s: %v: %s", dir, ref, err, strings.Join(lines, "\n"))
+}
+
+// cleanWorktree removes the files of the worktree of repo that are not in
+// its index, including ignored ones, like git clean -xfd. go-git's
+// Worktree.Clean keeps the ignored files.
+func cleanWorktree(repo *git.Repository) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %v", err)
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read the index: %v", err)
+	}
+	root := wt.Filesystem.Root()
+
+	// the tracked files, the submodules and their parent dirs are kept
+	tracked := map[string]bool{}
+	submodules := map[string]bool{}
+	for _, entry := range idx.Entries {
+		tracked[entry.Name] = true
+		if entry.Mode == filemode.Submodule {
+			submodules[entry.Name] = true
+		}
+		for dir := filepath.ToSlash(filepath.Dir(entry.Name)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			tracked[dir] = true
+		}
+	}
+
+	var removed int
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == git.GitDirName || submodules[rel] {
+			return filepath.SkipDir
+		}
+		if tracked[rel] {
+			return nil
+		}
+		removed++
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clean %s: %v", root, err)
+	}
+	klog.V(1).Infof("Removed %d untracked files and dirs from %s", removed, root)
+	return nil
+}
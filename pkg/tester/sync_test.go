@@ -0,0 +1,86 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestCleanWorktree(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"README.md", "pkg/a/a.go"} {
+		write(name, name+"\n")
+	}
+	write(".gitignore", "_output/\n*.log\n")
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("README.md", "modified\n")
+	write("untracked.txt", "untracked\n")
+	write("pkg/a/untracked.go", "untracked\n")
+	write("pkg/b/new.go", "untracked\n")
+	write("_output/bin/e2e.test", "ignored\n")
+	write("build.log", "ignored\n")
+
+	if err := cleanWorktree(repo); err != nil {
+		t.Fatalf("cleanWorktree() failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		kept bool
+	}{
+		{path: ".git", kept: true},
+		{path: ".gitignore", kept: true},
+		{path: "README.md", kept: true},
+		{path: "pkg/a/a.go", kept: true},
+		{path: "untracked.txt"},
+		{path: "pkg/a/untracked.go"},
+		{path: "pkg/b"},
+		{path: "_output"},
+		{path: "build.log"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(tc.path)))
+			if kept := err == nil; kept != tc.kept {
+				t.Errorf("%s kept: %v, want %v", tc.path, kept, tc.kept)
+			}
+		})
+	}
+
+	// modified tracked files are left to the reset
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	if err != nil || string(data) != "modified\n" {
+		t.Errorf("README.md = %q, %v, want the modified content", data, err)
+	}
+}
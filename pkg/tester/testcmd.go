@@ -0,0 +1,47 @@
+package tester
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kballard/go-shellquote"
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+)
+
+// runCommand runs the --test-cmd in the cloned repo, for repos whose e2e
+// tests are driven by their own scripts or make targets.
+func (t *Tester) runCommand() error {
+	tc, err := t.customTestCommand()
+	if err != nil {
+		return err
+	}
+	log, err := createTestLog()
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	env := mergeEnv(t.env, []string{
+		"KUBECONFIG=" + t.kubeconfigPath,
+		"ARTIFACTS=" + artifacts.BaseDir(),
+	})
+	klog.V(0).Infof("Running test command %q in %s", t.redact(t.TestCmd), tc.dir)
+	return t.runTestCmd(tc.command(env, log))
+}
+
+// customTestCommand returns the invocation of the --test-cmd.
+func (t *Tester) customTestCommand() (*testCommand, error) {
+	args, err := shellquote.Split(t.TestCmd)
+	if err != nil || len(args) == 0 {
+		return nil, fmt.Errorf("invalid --test-cmd %q: %v", t.redact(t.TestCmd), err)
+	}
+	tc := &testCommand{path: args[0], args: args[1:], dir: t.sourceDir()}
+	if t.TestWorkdir != "" {
+		tc.dir = t.TestWorkdir
+		if !filepath.IsAbs(tc.dir) {
+			tc.dir = filepath.Join(t.sourceDir(), tc.dir)
+		}
+	}
+	return tc, nil
+}
@@ -1,35 +1,224 @@
 package tester
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"strconv"
+	osexec "os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
-	git "github.com/go-git/go-git/v5"
-	"github.com/kballard/go-shellquote"
 	"github.com/octago/sflags/gen/gpflag"
 	"k8s.io/klog"
-	"sigs.k8s.io/kubetest2/pkg/artifacts"
-	"sigs.k8s.io/kubetest2/pkg/exec"
 	"sigs.k8s.io/kubetest2/pkg/testers"
 )
 
 var GitTag string
 
+// Options are the settings of a run, bound to the flags of the same names
+// by Execute(). Programs embedding the tester set them directly, starting
+// from NewDefaultOptions().
+type Options struct {
+	Config                  string        `desc:"JSON or YAML (.yaml, .yml) file mapping flag names to values, e.g. {\"focus-regex\": \"Conformance\"}. Flags given on the command line take precedence."`
+	FlakeAttempts           int           `desc:"Make up to this many attempts to run each spec."`
+	GinkgoArgs              string        `desc:"Additional arguments supported by the ginkgo binary."`
+	TestArgs                string        `desc:"Additional arguments passed to the test binary after the -- separator, e.g. \"--num-nodes=3\"."`
+	Parallel                int           `desc:"Run this many tests in parallel at once."`
+	SkipRegex               string        `desc:"Regular expression of jobs to skip."`
+	SkipFile                []string      `desc:"Files, absolute or relative to the cloned repo, of newline separated regular expressions added to --skip-regex."`
+	SkipURL                 []string      `desc:"URLs of maintained skip lists, e.g. the known failures of a provider, in the --skip-file format. They are fetched at run time and added to --skip-regex, and the last fetched copy is used when the fetch fails or with --offline."`
+	FocusRegex              string        `desc:"Regular expression of jobs to focus on."`
+	FocusFile               []string      `desc:"Files, absolute or relative to the cloned repo, of newline separated regular expressions added to --focus-regex."`
+	RerunFailedFrom         string        `desc:"Junit report of a previous run whose failed specs are the only ones to run. Replaces the focus regex, and succeeds without running anything when no spec failed."`
+	Suite                   []string      `desc:"Names of presets of focus, skip, label filter, parallelism and env settings defined in the suites file of the cloned repo. Flags take precedence over the presets. Several suites, e.g. --suite=parallel --suite=serial, run back to back against the same clone and cluster, each with its name and _ appended to the junit report prefix."`
+	SuitesFile              string        `desc:"Path, relative to the cloned repo, of the YAML or JSON file defining the --suite presets."`
+	LabelFilter             string        `desc:"Ginkgo v2 label filter query of the specs to run, e.g. '!Slow && !Flaky'."`
+	Seed                    int64         `desc:"Seed used by ginkgo to randomize the spec order. Defaults to a time based seed, which is recorded in the metadata."`
+	RandomizeAll            bool          `desc:"Randomize the order of all specs instead of only the top level containers."`
+	RandomizeSuites         bool          `desc:"Randomize the order in which test suites run."`
+	AutoSplit               bool          `desc:"Run the suite in two passes, following the upstream practice: the specs that are neither [Serial] nor [Disruptive] with --parallel nodes, then the [Serial] and [Disruptive] ones on a single node, with the parallel_ and serial_ junit report prefixes. With a focus, the serial specs are listed with a ginkgo v2 dry run."`
+	Conformance             bool          `desc:"Run the conformance specs serially and collect e2e.log and junit_01.xml into the conformance dir of the artifacts, as expected by conformance submissions."`
+	ShardIndex              int           `desc:"Index, starting at 0, of the shard of specs run by this invocation."`
+	ShardCount              int           `desc:"Number of invocations the specs are split across. Requires ginkgo v2."`
+	UntilItFails            bool          `desc:"Rerun the suite until it fails, to hunt flakes. In ginkgo run mode --timeout still bounds all the runs together."`
+	Repeat                  int           `desc:"Rerun the suite this many more times after it passes, stopping at the first failure. Requires ginkgo v2 in ginkgo run mode."`
+	Timeout                 time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete."`
+	TimeoutMargin           time.Duration `desc:"How long past --timeout the test processes may run before the tester terminates them, e.g. when ginkgo hangs during suite setup."`
+	SignalGracePeriod       time.Duration `desc:"How long to wait for the test processes to exit after forwarding SIGINT or SIGTERM before killing them."`
+	MonitorInterval         time.Duration `desc:"How often to sample the CPU and memory usage of the test processes and the disk usage of the run dir into resource-usage.csv in the artifacts dir. Zero disables the sampling, which is only supported on linux."`
+	HeartbeatInterval       time.Duration `desc:"How often to log that the tests are still running, with the elapsed time and the current phase, for CI systems that kill jobs without output. Zero disables the heartbeat."`
+	SpecProgress            bool          `desc:"Log the result of every spec as the ginkgo output reports it, and keep progress.json in the artifacts dir up to date with the counts of completed specs."`
+	Env                     []string      `desc:"List of KEY=VALUE env variables to pass to ginkgo libraries, on top of the inherited environment. $VAR references are expanded."`
+	EnvFile                 []string      `desc:"Dotenv style files of env variables to pass to ginkgo libraries. Entries of --env take precedence."`
+	EnvFromSecret           stringArray   `desc:"KEY=SECRET env variable of the test processes whose value is read at runtime from a secret: vault://PATH#FIELD, gsm://PROJECT/SECRET[/VERSION] or awssm://ARN[#FIELD]. Takes precedence over --env and --env-file, and is redacted from the logs. Can be repeated."`
+	DryRun                  bool          `desc:"Resolve the flags and paths, then print the test command, environment and working directory instead of cloning and running anything."`
+	ListTests               bool          `desc:"Clone and build or download the suite, then list the specs matching the focus, skip and label filters to stdout and specs.json in the artifacts dir instead of running them. Does not need a cluster."`
+	Offline                 bool          `desc:"Forbid network access: the repos must be local paths and the binaries and toolchains staged locally. Any download, including Go modules missing from the module cache, fails naming the missing input."`
+	LogFormat               string        `desc:"Format of the tester logs: text for klog, or json for JSON lines with the run id, phase and timing fields."`
+	RunID                   string        `desc:"Identifier of the run, included in the log lines, metadata, notifications and metrics, and exported to the tests as $KUBETEST2_RUN_ID. Defaults to $KUBETEST2_RUN_ID, or a random UUID. When set, it is also added to the names of the tester artifacts, e.g. build-<run id>.log."`
+	Kubeconfig              stringArray   `desc:"Path to the kubeconfig of the cluster under test. Can be repeated to merge several kubeconfigs, like a $KUBECONFIG path list, for multi-cluster suites. Defaults to $KUBECONFIG, then to the kubeconfig generated in the kubetest2 run dir."`
+	Context                 string        `desc:"Context of the kubeconfig to test against, instead of its current context."`
+	SocksProxy              string        `desc:"SOCKS5 proxy, e.g. socks5://localhost:1080, through which the API server is reached. Set as the proxy-url of the cluster in the kubeconfig given to the tests."`
+	SSHBastion              string        `desc:"SSH jump host, user@host[:port], through which the API server is reached, using a SOCKS5 proxy tunneled with ssh -D for the duration of the run."`
+	Server                  string        `desc:"URL of the API server to generate a kubeconfig for, for clusters with credentials but no kubeconfig file."`
+	CACert                  string        `desc:"Path to the CA certificate verifying the --server. The system roots are used otherwise."`
+	Token                   string        `desc:"Bearer token, e.g. of a service account, authenticating to the --server. Defaults to $KUBE_TOKEN."`
+	ExecCredential          string        `desc:"Command of an exec credential plugin authenticating to the --server, e.g. gke-gcloud-auth-plugin, instead of a --token."`
+	CreateKindCluster       bool          `desc:"Create a kind cluster to test against when no kubeconfig is available, and delete it after the run, to run the tester without a deployer."`
+	KindClusterName         string        `desc:"Name of the kind cluster of --create-kind-cluster."`
+	KindNodeImage           string        `desc:"Node image of the kind cluster of --create-kind-cluster, e.g. kindest/node:v1.29.2. Defaults to the image of the kind release."`
+	KindConfig              string        `desc:"Path to the kind config file of the cluster of --create-kind-cluster, e.g. for multiple nodes or feature gates."`
+	InCluster               bool          `desc:"Run the ginkgo suite in a job of the cluster under test, with a cluster-admin service account, instead of on the runner, for clusters whose network blocks the test traffic from outside. The test binaries must run on the cluster nodes. Logs are streamed and the artifacts copied back."`
+	InClusterImage          string        `desc:"Image of the job of --in-cluster the test binaries are copied to. Must have sh and tar."`
+	InClusterNamespace      string        `desc:"Namespace of the job of --in-cluster, kubetest2-gitremote-<run id> by default. It is created, and deleted afterwards, unless it exists."`
+	Provider                string        `desc:"Cloud provider of the cluster, e.g. gce, aws or skeleton, passed to the e2e test binary as --provider."`
+	GCEProject              string        `desc:"GCE project of the cluster, passed to the e2e test binary."`
+	GCEZone                 string        `desc:"GCE zone of the cluster, passed to the e2e test binary."`
+	GCERegion               string        `desc:"GCE region of the cluster, passed to the e2e test binary."`
+	ClusterTag              string        `desc:"Tag of the cloud resources of the cluster, passed to the e2e test binary."`
+	CloudConfigFile         string        `desc:"Cloud config file of the cluster, passed to the e2e test binary."`
+	Repo                    string        `desc:"Git repo to clone for the test. May be a local or http(s) git bundle file, ending in .bundle, which requires git to import."`
+	RepoMirrors             []string      `desc:"Alternate URLs of --repo, e.g. internal read-through mirrors, cloned in order when the clone of --repo fails. The mirror used is recorded in the metadata."`
+	Branch                  string        `desc:"Git branch to clone. Defaults to the remote default branch."`
+	Tag                     string        `desc:"Git tag to clone and check out. Annotated tags are resolved to the commit they point to and the tag is recorded in the metadata."`
+	Commit                  string        `desc:"Git revision (commit SHA) to check out after cloning."`
+	PR                      int           `desc:"Number of the GitHub pull request or GitLab merge request to check out after cloning its base --branch."`
+	PRMerge                 bool          `desc:"Merge the --pr into its base branch, like the merge commit GitHub tests, instead of checking out its head. Requires git."`
+	MergeTarget             string        `desc:"Branch to merge into the checked out revision before testing, failing early on conflicts. Requires git."`
+	VerifySignatures        string        `desc:"GPG public keyring (armored) or SSH allowed signers file. The cloned commit, the pull request head and base with --pr-merge, and the --merge-target tip must be signed by one of its keys. SSH signatures require git."`
+	SkipClone               bool          `desc:"Use the source already staged in the checkout dir instead of cloning the repo."`
+	CheckoutDir             string        `desc:"Directory to clone the repo into. Defaults to <run-dir>/src/<repo-name>."`
+	RepoPath                string        `desc:"Subdirectory of the cloned repo holding the tests, e.g. tests/e2e for monorepos. The build, go test, hooks and the paths relative to the cloned repo are resolved against it."`
+	RecurseSubmodules       bool          `desc:"Recursively clone the submodules of the repos."`
+	SparsePaths             []string      `desc:"Directories of the repo, e.g. test,hack, to check out instead of the whole tree. The git history is still fully cloned."`
+	CacheDir                string        `desc:"Directory holding bare mirrors of previously cloned repos. Clones are made from, and update, these mirrors when set."`
+	SyncStrategy            string        `desc:"What to do with an existing clone in the checkout dir: reset fetches and hard resets the worktree but keeps the ignored files, e.g. build outputs, clean also removes the ignored files like git clean -xfd, rebase rebases the local commits and changes onto the fetched revision, and fresh clones again."`
+	MinDisk                 string        `desc:"Free space, e.g. 20Gi, required on the filesystem of the checkout dir before cloning and building. Defaults to an estimate based on the repo and on whether the test package is built or downloaded. 0 disables the check."`
+	CloneRetries            int           `desc:"Number of times to retry a failed clone."`
+	CloneRetryInterval      time.Duration `desc:"How long to wait before the first clone retry. Doubles after each retry."`
+	QuietClone              bool          `desc:"Do not log the progress of clones and fetches, which is otherwise logged every 10s."`
+	ExtraRepos              []string      `desc:"Additional git repos (optionally suffixed with #<branch>) cloned next to the checkout dir before building."`
+	SSHPrivateKey           string        `desc:"Path to the SSH private key used to clone the repo. Defaults to $SSH_PRIVATE_KEY."`
+	SSHKnownHosts           string        `desc:"Path to the known_hosts file used to verify the git server. Defaults to $SSH_KNOWN_HOSTS."`
+	GitToken                string        `desc:"Token used to clone the repo over HTTPS. Defaults to $GIT_TOKEN."`
+	GitAuth                 string        `desc:"Credential provider of the clone auth: env (default) uses --git-token, --ssh-private-key or --github-app-id, file reads a token from the --git-secret path, github-app, gcp-secret-manager and vault read a token from the --git-secret secret."`
+	GitSecret               string        `desc:"Token file of --git-auth=file, projects/PROJECT/secrets/SECRET[/versions/VERSION] of --git-auth=gcp-secret-manager, or PATH#FIELD of --git-auth=vault."`
+	GithubAppID             string        `desc:"ID of the GitHub App whose installation token is used to clone the repo over HTTPS, instead of a long-lived --git-token."`
+	GithubAppInstallationID string        `desc:"ID of the installation of the --github-app-id GitHub App on the repo owner."`
+	GithubAppPrivateKey     string        `desc:"Path to the PEM private key of the --github-app-id GitHub App. Defaults to $GITHUB_APP_PRIVATE_KEY."`
+	GithubAPIEndpoint       string        `desc:"URL of the GitHub API the --github-app-id installation tokens are created with, e.g. https://github.example.com/api/v3 for GitHub Enterprise."`
+	GitProxy                string        `desc:"URL of the proxy used for HTTP(S) git remotes, e.g. http://proxy:3128. Defaults to $HTTPS_PROXY and $HTTP_PROXY, which the binary downloads always use. Hosts in $NO_PROXY are reached directly."`
+	GitCABundle             string        `desc:"Path to a PEM bundle of CA certificates trusted, in addition to the system roots, to verify HTTPS git servers, e.g. self-hosted ones with a private CA."`
+	GitInsecureSkipVerify   bool          `desc:"Do not verify the TLS certificates of HTTPS git servers. Insecure, prefer --git-ca-bundle."`
+	RedactPattern           string        `desc:"Case-insensitive regular expression of the names of flags, and of --env and --env-file variables, whose values are masked in logs and dry run output. Empty to only mask the git token."`
+	GoVersion               string        `desc:"Go toolchain version, e.g. 1.21.3, downloaded into the run dir and used to build and run the tests instead of the Go on PATH. auto uses the toolchain required by the go.mod of the cloned repo. Ignored when GOTOOLCHAIN selects a toolchain."`
+	BuildCmd                string        `desc:"Command run inside the cloned repo to build the ginkgo, e2e.test and kubectl binaries. Empty to use binaries already present in --build-out-dir."`
+	BuildSystem             string        `desc:"How to build the test package: make runs --build-cmd, bazel builds --bazel-test-target and --bazel-targets, auto uses bazel for repos with a Bazel workspace and no Makefile."`
+	BazelTestTarget         string        `desc:"Bazel target of the e2e test binary with --build-system=bazel, located under bazel-bin once built."`
+	BazelTargets            []string      `desc:"Other Bazel targets built with --build-system=bazel. The ginkgo and kubectl binaries among their outputs are used."`
+	BuildGOOS               string        `desc:"OS to build the e2e test binary for, e.g. to run it on the cluster nodes. Defaults to the first non host platform of $KUBE_BUILD_PLATFORMS, or the host OS."`
+	BuildGOARCH             string        `desc:"Architecture, e.g. arm64, to build the e2e test binary for. Defaults like --build-goos."`
+	BuildTimeout            time.Duration `desc:"How long the build command may run. Zero means no limit."`
+	BuildOutDir             string        `desc:"Directory, relative to the cloned repo, where the build command places its binaries."`
+	TestWorkdir             string        `desc:"Directory, relative to the cloned repo, that ginkgo is run from. Defaults to the current working directory."`
+	TestBinaryPath          string        `desc:"Path, relative to the cloned repo, of the compiled test binary or Go test package run by ginkgo. Defaults to the e2e.test binary of the test package."`
+	GinkgoBinary            string        `desc:"Path or name on PATH of a ginkgo binary to use instead of the one of the test package, which is then not built."`
+	GinkgoVersion           string        `desc:"Version of ginkgo v2 to go install, e.g. v2.13.0, instead of using the one of the test package. Ginkgo is also installed, at the version required by the cloned repo, when the build doesn't produce it."`
+
+	PreTestCmd  stringArray `desc:"Shell command run in the cloned repo before the tests. Can be repeated."`
+	PostTestCmd stringArray `desc:"Shell command run in the cloned repo after the tests, even if they failed. Can be repeated."`
+
+	AcquireKubectl           bool          `desc:"Download a kubectl matching the cluster version into the run dir when none was built or found on PATH."`
+	DumpClusterOnFailure     bool          `desc:"Dump the cluster state, events and node descriptions to the artifacts dir when the tests fail."`
+	DebugShell               bool          `desc:"Start an interactive shell in the checkout dir, with $KUBECONFIG and the test env exported, when the tests fail and stdin is a terminal. The run continues when the shell exits."`
+	TestNamespace            string        `desc:"Namespace passed to the e2e tests as --test-namespace, for suites that support running in a given namespace."`
+	DeleteNamespaceOnFailure bool          `desc:"Let the e2e tests delete the namespaces of failed specs. Disable to keep them for debugging."`
+	SweepNamespaces          bool          `desc:"When the tests fail, delete the namespaces labeled by the e2e framework that were created during the run and left behind, e.g. by a crashed test binary. Not safe on clusters shared by concurrent runs."`
+	Preflight                bool          `desc:"Check that the kubeconfig is valid and the API server reachable before the tests, and before the clone and build when kubectl is on PATH."`
+	PreflightTimeout         time.Duration `desc:"How long the preflight checks retry before failing."`
+	WaitForNodes             int           `desc:"Wait until this many nodes are Ready before starting the tests."`
+	NodeReadyTimeout         time.Duration `desc:"How long to wait for --wait-for-nodes nodes to be Ready."`
+	AllowSkew                bool          `desc:"Only warn, instead of failing, when the cluster version is more than one minor version away from the version of the tests, as given by --test-package-version, or the --tag or release-X.Y --branch of the kubernetes repo, else the closest release tag of its checkout."`
+	SuiteRetries             int           `desc:"Rerun the whole suite up to this many times when it fails while the cluster is unhealthy, i.e. its API server is unreachable or nodes are NotReady. Unlike --flake-attempts this does not retry specs that fail on a healthy cluster."`
+	SuiteRetryInterval       time.Duration `desc:"How long to wait before the first --suite-retries rerun, doubling after each rerun."`
+
+	JUnit             bool   `flag:"junit" desc:"Write junit_*.xml reports to the artifacts dir and fail if none are produced."`
+	JUnitReportPrefix string `flag:"junit-report-prefix" desc:"Prefix of the junit report file names, e.g. serial_ for junit_serial_01.xml."`
+	JSONReport        bool   `desc:"Write the ginkgo v2 JSON report to <junit report prefix>report.json in the artifacts dir and summarize it in the metadata: the spec counts by state, the labels of the failed specs, the retried specs and the start, end and slowest spec of the run."`
+
+	MetricsGateway string `desc:"URL of a Prometheus Pushgateway to push the run metrics to when the run finishes, e.g. http://pushgateway:9091."`
+	MetricsJob     string `desc:"Job name the metrics are pushed under. Defaults to $JOB_NAME, or kubetest2-tester-gitremote."`
+
+	NotifyWebhook  string `desc:"URL to POST a JSON summary of the run to when it finishes, e.g. a Slack incoming webhook."`
+	NotifyTemplate string `desc:"Go template of the --notify-webhook payload, executed with the fields of the default JSON summary, e.g. {\"text\": {{json .Result}}}. The json function quotes a value as a JSON string."`
+	ArtifactsURL   string `desc:"URL where the artifacts of the run can be browsed, included in notifications. Defaults to the console URL of --artifact-upload."`
+
+	ArtifactUpload string `desc:"Bucket URL, gs://bucket/prefix or s3://bucket/prefix, to sync the artifacts dir to after the run with gsutil or aws."`
+
+	RunMode     string   `desc:"How to run the suite: ginkgo runs the ginkgo binary, go-test runs go test inside the cloned repo, command runs --test-cmd."`
+	TestCmd     string   `desc:"Command, e.g. \"make e2e\", run in the cloned repo with $KUBECONFIG and $ARTIFACTS exported instead of ginkgo, for repos wrapping their e2e tests in scripts. Implies --run-mode=command."`
+	GoTestPkgs  []string `desc:"Packages, relative to the cloned repo, passed to go test in go-test run mode."`
+	GoTestRun   string   `desc:"Regular expression passed to go test -run in go-test run mode."`
+	GoTestCount int      `desc:"Value passed to go test -count in go-test run mode."`
+
+	TestPackageVersion  string `desc:"Download the test package of this kubernetes release (e.g. v1.28.0, or latest) instead of building it from the cloned repo."`
+	TestPackageBucket   string `desc:"The bucket which release tars will be downloaded from to acquire the test package."`
+	TestPackageDir      string `desc:"The directory in the bucket which represents the type of release."`
+	TestPackageChecksum string `desc:"Expected sha256 of the release tar, checked instead of its published .sha256 file. Downloads failing their checksum are deleted and never run."`
+}
+
+// Tester runs the suite of a git repo with its Options, see Run().
 type Tester struct {
-	FlakeAttempts int           `desc:"Make up to this many attempts to run each spec."`
-	GinkgoArgs    string        `desc:"Additional arguments supported by the ginkgo binary."`
-	Parallel      int           `desc:"Run this many tests in parallel at once."`
-	SkipRegex     string        `desc:"Regular expression of jobs to skip."`
-	FocusRegex    string        `desc:"Regular expression of jobs to focus on."`
-	Timeout       time.Duration `desc:"How long (in golang duration format) to wait for ginkgo tests to complete."`
-	Env           []string      `desc:"List of env variables to pass to ginkgo libraries"`
-	Repo          string        `desc:"Git repo to clone for the test."`
+	Options
+
+	// ctx is the context of Run()
+	ctx context.Context
 
 	kubeconfigPath string
-	runDir         string
+	// kubeconfigs are merged into kubeconfigPath, see mergeKubeconfigs()
+	kubeconfigs []string
+	// githubApp is the --github-app-id auth, see gitAuth()
+	githubApp *githubAppAuth
+	// tunnel is the ssh process of --ssh-bastion, see startTunnel()
+	tunnel    *osexec.Cmd
+	tunnelURL string
+	// inClusterCreatedNamespace is set when the namespace of --in-cluster
+	// was created by this run, see applyInClusterJob()
+	inClusterCreatedNamespace bool
+	// kindCluster is the cluster created for --create-kind-cluster
+	kindCluster string
+	runDir      string
+	// runID identifies the run in the logs and artifacts, see resolveRunID()
+	runID string
+	// env is the environment of the test processes, see resolveEnv()
+	env []string
+	// envOverrides are the entries of env that are not inherited
+	envOverrides []string
+	// secretEnv are the resolved --env-from-secret entries
+	secretEnv []string
+	// skipLists are the patterns of the --skip-url lists, see skipListPatterns()
+	skipLists map[string][]string
+	// redactRegexp is the compiled --redact-pattern, see redact()
+	redactRegexp *regexp.Regexp
+	// rerunNothing is set when --rerun-failed-from has no failed specs
+	rerunNothing bool
+	// suitePass is the name of the pass being run by runSuites(), if any
+	suitePass string
+	// gitCommit is the commit of the cloned repo that is tested
+	gitCommit string
+	// durations are the durations of the run phases, see timed()
+	durations map[string]time.Duration
+	// phase is the running phase and when it started, see timed()
+	phaseMu      sync.Mutex
+	phase        string
+	phaseStarted time.Time
 
 	// These paths are set up by AcquireTestPackage()
 	e2eTestPath string
@@ -38,6 +227,7 @@ type Tester struct {
 }
 
 func (t *Tester) Execute() error {
+	start := time.Now()
 	fs, err := gpflag.Parse(t)
 	if err != nil {
 		return fmt.Errorf("failed to initialize tester: %v", err)
@@ -57,12 +247,42 @@ func (t *Tester) Execute() error {
 		return nil
 	}
 
+	if t.Config != "" {
+		if err := applyConfig(fs, t.Config); err != nil {
+			return err
+		}
+	}
+	t.addDuration("flags", time.Since(start))
+	return t.Run(context.Background())
+}
+
+// Run runs the tests with the Options of t until they are done or ctx is
+// canceled, which terminates the test processes like SIGTERM does. Unlike
+// Execute() it reads no command line flags, for programs embedding the
+// tester.
+func (t *Tester) Run(ctx context.Context) error {
+	t.ctx = ctx
+	if err := t.resolveRunID(); err != nil {
+		return err
+	}
+	if err := t.configureLogging(); err != nil {
+		return err
+	}
 	if err := t.initKubetest2Info(); err != nil {
 		return err
 	}
 	return t.Test()
 }
 
+// context returns the context of Run(), for the tester to be usable without
+// it as well.
+func (t *Tester) context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
 // initializes relevant information from the well defined kubetest2 environment variables.
 func (t *Tester) initKubetest2Info() error {
 	if dir, ok := os.LookupEnv("KUBETEST2_RUN_DIR"); ok {
@@ -78,74 +298,544 @@ func (t *Tester) initKubetest2Info() error {
 	return nil
 }
 
-func (t *Tester) Test() error {
+func (t *Tester) Test() (err error) {
+	start := time.Now()
+	defer t.stopTunnel()
+	defer t.deleteKindCluster()
+	defer func() {
+		if t.NotifyWebhook == "" || t.DryRun || t.ListTests {
+			return
+		}
+		if notifyErr := t.notify(err, time.Since(start)); notifyErr != nil {
+			klog.Warningf("failed to send notification: %v", notifyErr)
+		}
+	}()
+	defer func() {
+		if t.ArtifactUpload == "" || t.DryRun || t.ListTests {
+			return
+		}
+		if uploadErr := t.timed("artifacts", t.uploadArtifacts); uploadErr != nil {
+			klog.Warningf("failed to upload artifacts: %v", uploadErr)
+		}
+	}()
+	// before the upload, for the metadata to include the timings
+	defer func() {
+		if !t.DryRun {
+			t.summarizePhases(time.Since(start))
+		}
+	}()
+	defer func() {
+		if err == nil {
+			return
+		}
+		if metaErr := addMetadata(map[string]string{"failure-reason": string(classOf(err))}); metaErr != nil {
+			klog.Warningf("failed to write failure reason to metadata: %v", metaErr)
+		}
+	}()
+	defer func() {
+		if t.MetricsGateway == "" || t.DryRun || t.ListTests {
+			return
+		}
+		if pushErr := t.pushMetrics(err); pushErr != nil {
+			klog.Warningf("failed to push metrics: %v", pushErr)
+		}
+	}()
+
+	if err := t.setup(); err != nil {
+		return classify(failureInfra, err)
+	}
+
+	if t.DryRun {
+		return t.printDryRun(os.Stdout)
+	}
+	if t.rerunNothing {
+		klog.V(0).Infof("No failed specs in %s, nothing to rerun", t.RerunFailedFrom)
+		return nil
+	}
+	if t.ListTests {
+		return classify(failureInfra, t.printSpecs(os.Stdout))
+	}
+
+	if err := t.timed("hooks", func() error { return t.runHooks("pre-test", t.PreTestCmd) }); err != nil {
+		return classify(failureInfra, err)
+	}
+
+	run := t.runGinkgo
+	switch t.RunMode {
+	case runModeGoTest:
+		run = t.runGoTest
+	case runModeCommand:
+		run = t.runCommand
+	}
+	testErr := t.timed("test", func() error { return t.runSuites(run) })
+	if errors.Is(testErr, errTesterTimeout) {
+		testErr = classify(failureTimeout, testErr)
+	}
+	testErr = classify(failureTest, testErr)
+
+	_ = t.timed("artifacts", func() error {
+		t.collectArtifacts(testErr, start)
+		return nil
+	})
+
+	if testErr != nil && t.DebugShell {
+		t.debugShell()
+	}
+
+	// post-test hooks run regardless of the test result, e.g. to collect logs
+	if err := t.timed("hooks", func() error { return t.runHooks("post-test", t.PostTestCmd) }); err != nil {
+		if testErr != nil {
+			klog.Warning(err)
+			return testErr
+		}
+		return classify(failureInfra, err)
+	}
+	return testErr
+}
+
+// collectArtifacts collects the results and the cluster state of a run that
+// started at start and ended with testErr into the artifacts dir.
+func (t *Tester) collectArtifacts(testErr error, start time.Time) {
+	if t.Conformance {
+		if err := collectConformanceResults(); err != nil {
+			klog.Warning(err)
+		}
+	}
+	if testErr != nil && t.DumpClusterOnFailure {
+		t.dumpClusterState()
+	}
+	// namespaces of failed specs are kept on purpose otherwise
+	if testErr != nil && t.SweepNamespaces && t.DeleteNamespaceOnFailure {
+		if err := t.sweepNamespaces(start); err != nil {
+			klog.Warningf("failed to sweep namespaces: %v", err)
+		}
+	}
+	if err := t.writeResultMetadata(); err != nil {
+		klog.Warningf("failed to write result metadata: %v", err)
+	}
+	if t.JUnit {
+		if err := t.writeTestDurations(); err != nil {
+			klog.Warningf("failed to write test durations: %v", err)
+		}
+	}
+}
+
+// setup prepares everything the tests need: the environment, the cloned
+// repo, the test binaries and the kubeconfig, and checks that the cluster
+// is reachable.
+func (t *Tester) setup() error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	if err := t.resolveRunID(); err != nil {
+		return err
+	}
+	klog.V(0).Infof("Run ID: %s", t.runID)
+	if !t.DryRun {
+		if err := addMetadata(map[string]string{"run-id": t.runID}); err != nil {
+			return err
+		}
+	}
+
+	env, err := t.resolveEnv()
+	if err != nil {
+		return err
+	}
+	t.env = env
+
+	if t.DryRun {
+		// resolve paths without cloning, building or downloading anything
+		if err := t.setCheckoutDir(); err != nil {
+			return err
+		}
+		if len(t.Suite) > 0 {
+			if len(t.Suite) > 1 {
+				klog.V(0).Infof("dry run: showing the command of suite %q, the first of %v", t.Suite[0], t.Suite)
+			}
+			if err := t.loadSuite(t.Suite[0]); err != nil {
+				klog.Warningf("dry run: %v", err)
+			}
+		}
+		if err := t.loadPatternFiles(); err != nil {
+			klog.Warningf("dry run: %v", err)
+		}
+		if t.AutoSplit {
+			klog.V(0).Infof("dry run: --auto-split runs the command twice, skipping then running the specs of the focus matching %s with --parallel=1", serialSpecs)
+		}
+		if t.RunMode == runModeGinkgo {
+			t.setTestPackagePaths()
+		}
+		if err := t.resolveKubeconfig(); err != nil {
+			if t.CreateKindCluster {
+				t.kubeconfigPath = filepath.Join(t.runDir, kindKubeconfig)
+			} else {
+				klog.Warningf("dry run: %v", err)
+			}
+		}
+		if len(t.kubeconfigs) > 0 {
+			t.kubeconfigPath = filepath.Join(t.runDir, mergedKubeconfig)
+		}
+		return nil
+	}
 
 	if err := testers.WriteVersionToMetadata(GitTag); err != nil {
 		return err
 	}
 
+	// listing specs doesn't talk to the cluster
+	kubeconfigErr := t.resolveKubeconfig()
+	if kubeconfigErr != nil && t.CreateKindCluster && !t.ListTests {
+		if err := t.createKindCluster(); err != nil {
+			return err
+		}
+		kubeconfigErr = t.resolveKubeconfig()
+	}
+	if kubeconfigErr != nil && !t.ListTests {
+		return kubeconfigErr
+	}
+
+	// check the cluster before the clone and build when a kubectl is
+	// already available, so that an unreachable cluster fails fast
+	checked := false
+	if _, err := osexec.LookPath("kubectl"); err == nil && !t.ListTests {
+		if err := t.mergeKubeconfigs(); err != nil {
+			return err
+		}
+		if t.Preflight {
+			if err := t.timed("preflight", t.checkCluster); err != nil {
+				return err
+			}
+			checked = true
+		}
+	}
+
 	if err := t.pretestSetup(); err != nil {
 		return err
 	}
 
-	if t.kubeconfigPath == "" {
-		if kubeconfig, ok := os.LookupEnv("KUBECONFIG"); ok {
-			t.kubeconfigPath = kubeconfig
-		} else {
-			return fmt.Errorf("kubeconfig path not provided")
+	if t.ListTests {
+		if kubeconfigErr != nil {
+			klog.V(1).Infof("list tests: %v", kubeconfigErr)
 		}
+		return nil
 	}
 
-	e2eTestArgs := []string{
-		"--kubeconfig=" + t.kubeconfigPath,
-		"--ginkgo.skip=" + t.SkipRegex,
-		"--ginkgo.focus=" + t.FocusRegex,
-		"--report-dir=" + artifacts.BaseDir(),
-		"--ginkgo.timeout=" + t.Timeout.String(),
+	if t.AcquireKubectl {
+		if err := t.acquireKubectl(); err != nil {
+			return fmt.Errorf("failed to acquire kubectl: %v", err)
+		}
+	}
+	if err := t.mergeKubeconfigs(); err != nil {
+		return err
 	}
+	return t.timed("preflight", func() error {
+		if t.Preflight && !checked {
+			if err := t.checkCluster(); err != nil {
+				return err
+			}
+		}
+		if err := t.checkVersionSkew(); err != nil {
+			return err
+		}
+		if t.WaitForNodes > 0 {
+			return t.waitForNodes()
+		}
+		return nil
+	})
+}
 
-	extraGingkoArgs, err := shellquote.Split(t.GinkgoArgs)
-	if err != nil {
-		return fmt.Errorf("error parsing --gingko-args: %v", err)
+// validate checks that the combination of flags is supported.
+func (t *Tester) validate() error {
+	if t.TestCmd != "" {
+		if t.RunMode == runModeGoTest {
+			return fmt.Errorf("--test-cmd can't be used in %s run mode", runModeGoTest)
+		}
+		t.RunMode = runModeCommand
+	}
+	if err := t.compileRedactPattern(); err != nil {
+		return err
+	}
+	if t.Conformance {
+		if err := t.applyConformance(); err != nil {
+			return err
+		}
+	}
+	// fail now rather than after the tests ran
+	if _, err := t.notificationPayload(notification{}); err != nil {
+		return err
+	}
+	if t.ArtifactUpload != "" {
+		if _, err := t.uploadCommand(); err != nil {
+			return err
+		}
+	}
+	switch t.RunMode {
+	case runModeGinkgo, runModeGoTest:
+	case runModeCommand:
+		if t.TestCmd == "" {
+			return fmt.Errorf("%s run mode requires --test-cmd", runModeCommand)
+		}
+	default:
+		return fmt.Errorf("unsupported --run-mode %q, must be one of %q, %q or %q", t.RunMode, runModeGinkgo, runModeGoTest, runModeCommand)
+	}
+	if t.ShardCount < 1 || t.ShardIndex < 0 || t.ShardIndex >= t.ShardCount {
+		return fmt.Errorf("--shard-index must be in [0, --shard-count), got %d of %d", t.ShardIndex, t.ShardCount)
+	}
+	if err := t.validateRefs(); err != nil {
+		return err
+	}
+	if err := t.validateSyncStrategy(); err != nil {
+		return err
+	}
+	if err := t.validateSkipURLs(); err != nil {
+		return err
+	}
+	if t.Offline {
+		if err := t.validateOffline(); err != nil {
+			return err
+		}
+	}
+	if t.RepoPath != "" && !filepath.IsLocal(t.RepoPath) {
+		return fmt.Errorf("--repo-path must be a relative path inside the cloned repo, got %q", t.RepoPath)
 	}
-	ginkgoArgs := append(extraGingkoArgs,
-		"--nodes="+strconv.Itoa(t.Parallel),
-		t.e2eTestPath,
-		"--")
-	ginkgoArgs = append(ginkgoArgs, e2eTestArgs...)
+	switch t.BuildSystem {
+	case buildSystemAuto, buildSystemMake, buildSystemBazel:
+	default:
+		return fmt.Errorf("unsupported --build-system %q, must be one of %q, %q or %q", t.BuildSystem, buildSystemAuto, buildSystemMake, buildSystemBazel)
+	}
+	if t.BuildSystem == buildSystemBazel && (t.BuildGOOS != "" || t.BuildGOARCH != "") {
+		return fmt.Errorf("--build-goos and --build-goarch are not supported with --build-system=%s", buildSystemBazel)
+	}
+	if t.TestPackageChecksum != "" {
+		if t.TestPackageVersion == "" || t.TestPackageVersion == "latest" {
+			return fmt.Errorf("--test-package-checksum requires a pinned --test-package-version")
+		}
+		if sum, err := hex.DecodeString(t.TestPackageChecksum); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("--test-package-checksum must be a hex encoded sha256, got %q", t.TestPackageChecksum)
+		}
+	}
+	if (t.BuildGOOS != "" || t.BuildGOARCH != "") && t.TestPackageVersion != "" {
+		return fmt.Errorf("--build-goos and --build-goarch can't be used with --test-package-version")
+	}
+	if t.Repeat < 0 {
+		return fmt.Errorf("--repeat must not be negative, got %d", t.Repeat)
+	}
+	if t.UntilItFails && t.Repeat > 0 {
+		return fmt.Errorf("--until-it-fails and --repeat are mutually exclusive")
+	}
+	if _, ok := credentialProviders[t.GitAuth]; t.GitAuth != "" && !ok {
+		return fmt.Errorf("unknown --git-auth %q, must be one of %s", t.GitAuth, strings.Join(credentialProviderNames(), ", "))
+	}
+	if t.Server != "" && len(t.Kubeconfig) > 0 {
+		return fmt.Errorf("--server and --kubeconfig are mutually exclusive")
+	}
+	if t.Server == "" && (t.CACert != "" || t.Token != "" || t.ExecCredential != "") {
+		return fmt.Errorf("--ca-cert, --token and --exec-credential require --server")
+	}
+	if t.Token != "" && t.ExecCredential != "" {
+		return fmt.Errorf("--token and --exec-credential are mutually exclusive")
+	}
+	if !t.CreateKindCluster && (t.KindNodeImage != "" || t.KindConfig != "") {
+		return fmt.Errorf("--kind-node-image and --kind-config require --create-kind-cluster")
+	}
+	if t.SocksProxy != "" && t.SSHBastion != "" {
+		return fmt.Errorf("--socks-proxy and --ssh-bastion are mutually exclusive")
+	}
+	if t.RerunFailedFrom != "" && t.RunMode != runModeGinkgo {
+		return fmt.Errorf("--rerun-failed-from is not supported in %s run mode", t.RunMode)
+	}
+	if t.InCluster && t.RunMode != runModeGinkgo {
+		return fmt.Errorf("--in-cluster is not supported in %s run mode", t.RunMode)
+	}
+	if t.ListTests && t.RunMode != runModeGinkgo {
+		return fmt.Errorf("--list-tests is not supported in %s run mode", t.RunMode)
+	}
+	if t.AutoSplit {
+		if err := t.validateAutoSplit(); err != nil {
+			return err
+		}
+	}
+	if t.ListTests && len(t.Suite) > 1 {
+		return fmt.Errorf("--list-tests lists the specs of a single --suite, got %v", t.Suite)
+	}
+	if t.ShardCount > 1 && t.RunMode != runModeGinkgo {
+		return fmt.Errorf("sharding is not supported in %s run mode", t.RunMode)
+	}
+	return nil
+}
 
-	klog.V(0).Infof("Running ginkgo test as %s %+v", t.ginkgoPath, ginkgoArgs)
-	cmd := exec.Command(t.ginkgoPath, ginkgoArgs...)
-	cmd.SetEnv(t.Env...)
-	exec.InheritOutput(cmd)
-	return cmd.Run()
+// sourceDir returns the dir of the cloned repo holding the tests, see
+// --repo-path.
+func (t *Tester) sourceDir() string {
+	return filepath.Join(t.CheckoutDir, t.RepoPath)
+}
+
+// setCheckoutDir defaults and absolutizes the checkout dir.
+func (t *Tester) setCheckoutDir() error {
+	if t.CheckoutDir == "" {
+		t.CheckoutDir = filepath.Join(t.runDir, "src", repoName(t.Repo))
+	}
+	// paths derived from the checkout dir must survive --test-workdir
+	dir, err := filepath.Abs(t.CheckoutDir)
+	if err != nil {
+		return fmt.Errorf("failed to convert checkout dir to absolute path: %v", err)
+	}
+	t.CheckoutDir = dir
+	return nil
 }
 
 func (t *Tester) pretestSetup() error {
+	if err := t.setCheckoutDir(); err != nil {
+		return err
+	}
 
-	_, err := git.PlainClone(t.runDir, false, &git.CloneOptions{
-		URL: t.Repo,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to clone repo: %v", err)
+	if err := t.checkDiskSpace(); err != nil {
+		return err
+	}
+
+	// the test package download and the clone are both network bound and
+	// independent of each other
+	var waitDownload func() error
+	if t.TestPackageVersion != "" && t.RunMode == runModeGinkgo {
+		ctx, cancel := context.WithCancel(t.context())
+		waitDownload = t.startTestPackageDownload(ctx)
+		// don't leave the download running when the setup fails early
+		defer func() {
+			cancel()
+			waitDownload()
+		}()
+	}
+
+	cloneStart := time.Now()
+	if err := t.timed("clone", t.cloneRepo); err != nil {
+		return err
+	}
+	if waitDownload != nil {
+		klog.V(0).Infof("Clone finished in %v", time.Since(cloneStart).Round(time.Second))
+	}
+
+	if t.RepoPath != "" {
+		if info, err := os.Stat(t.sourceDir()); err != nil || !info.IsDir() {
+			return fmt.Errorf("--repo-path %s is not a directory of the cloned repo", t.RepoPath)
+		}
+	}
+
+	if len(t.Suite) > 1 {
+		// the suites and the pattern files are loaded in turn by runSuites()
+		if err := t.checkSuites(); err != nil {
+			return err
+		}
+	} else {
+		if len(t.Suite) == 1 {
+			if err := t.loadSuite(t.Suite[0]); err != nil {
+				return err
+			}
+		}
+		if err := t.loadPatternFiles(); err != nil {
+			return err
+		}
+	}
+
+	if t.GoVersion == goVersionAuto {
+		if err := t.resolveGoVersion(); err != nil {
+			return err
+		}
+	}
+	if t.GoVersion != "" {
+		if err := t.installGo(); err != nil {
+			return err
+		}
+	}
+	if err := t.checkGoMod(); err != nil {
+		return err
+	}
+
+	// go test compiles the suite itself, and the --test-cmd does whatever
+	// it needs
+	if t.RunMode != runModeGinkgo {
+		return nil
+	}
+
+	if waitDownload != nil {
+		if err := waitDownload(); err != nil {
+			return fmt.Errorf("failed to acquire test package: %v", err)
+		}
+		if err := t.timed("build", t.checkTestPackage); err != nil {
+			return fmt.Errorf("failed to acquire test package: %v", err)
+		}
+		return nil
+	}
+	if err := t.timed("build", t.AcquireTestPackage); err != nil {
+		return fmt.Errorf("failed to acquire test package: %v", err)
 	}
 
 	return nil
 }
 
-func NewDefaultTester() *Tester {
+// NewDefaultOptions returns the Options of the flag defaults.
+func NewDefaultOptions() Options {
+	return Options{
+		FlakeAttempts:            1,
+		Parallel:                 1,
+		ShardCount:               1,
+		SuitesFile:               "suites.yaml",
+		Timeout:                  24 * time.Hour,
+		SignalGracePeriod:        30 * time.Second,
+		TimeoutMargin:            10 * time.Minute,
+		MonitorInterval:          30 * time.Second,
+		HeartbeatInterval:        5 * time.Minute,
+		SpecProgress:             true,
+		LogFormat:                logFormatText,
+		Env:                      nil,
+		JUnit:                    true,
+		JSONReport:               true,
+		DumpClusterOnFailure:     true,
+		DeleteNamespaceOnFailure: true,
+		Preflight:                true,
+		PreflightTimeout:         2 * time.Minute,
+		NodeReadyTimeout:         10 * time.Minute,
+		SuiteRetryInterval:       time.Minute,
+		KindClusterName:          "kubetest2-gitremote",
+		InClusterImage:           "registry.k8s.io/e2e-test-images/busybox:1.36.1-1",
+		AcquireKubectl:           true,
+		RunMode:                  runModeGinkgo,
+		CloneRetries:             3,
+		SyncStrategy:             syncStrategyReset,
+		CloneRetryInterval:       5 * time.Second,
+		GithubAPIEndpoint:        "https://api.github.com",
+		BuildCmd:                 defaultBuildCmd,
+		BuildSystem:              buildSystemAuto,
+		BazelTestTarget:          "//test/e2e:e2e_test",
+		BazelTargets:             []string{"//vendor/github.com/onsi/ginkgo/v2/ginkgo", "//cmd/kubectl"},
+		BuildOutDir:              "_output/bin",
+		GoTestPkgs:               []string{"./test/e2e/..."},
+		GoTestCount:              1,
+		RedactPattern:            defaultRedactPattern,
 
-	return &Tester{
-		FlakeAttempts: 1,
-		Parallel:      1,
-		Timeout:       24 * time.Hour,
-		Env:           nil,
+		TestPackageBucket: "kubernetes-release",
+		TestPackageDir:    "release",
 	}
 }
 
+// New returns a tester running with opts.
+func New(opts Options) *Tester {
+	return &Tester{Options: opts}
+}
+
+func NewDefaultTester() *Tester {
+	return New(NewDefaultOptions())
+}
+
+// Validate checks opts the way Run() does before doing anything.
+func (opts Options) Validate() error {
+	return New(opts).validate()
+}
+
 func Main() {
 	t := NewDefaultTester()
 	if err := t.Execute(); err != nil {
-		klog.Fatalf("failed to run ginkgo tester: %v", err)
+		klog.Errorf("failed to run ginkgo tester (%s failure): %v", classOf(err), err)
+		klog.Flush()
+		os.Exit(exitCode(err))
 	}
 }
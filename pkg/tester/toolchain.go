@@ -0,0 +1,156 @@
+package tester
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"k8s.io/klog"
+)
+
+// goDownloadURL is where the Go toolchain archives and their checksums are
+// published.
+const goDownloadURL = "https://dl.google.com/go"
+
+// installGo makes the Go toolchain --go-version the one used by the build,
+// hooks and tests, downloading it into the run dir unless it is already
+// there. An explicit GOTOOLCHAIN takes precedence.
+func (t *Tester) installGo() error {
+	if v := envValue(t.env, "GOTOOLCHAIN"); v != "" && v != "auto" && v != "local" {
+		klog.Warningf("GOTOOLCHAIN=%s selects the Go toolchain, ignoring --go-version", v)
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("--go-version is not supported on windows")
+	}
+
+	version := "go" + goReleaseVersion(strings.TrimPrefix(t.GoVersion, "go"))
+	goroot := filepath.Join(t.runDir, "toolchains", version)
+	if _, err := os.Stat(filepath.Join(goroot, "bin", "go")); err != nil {
+		if t.Offline {
+			return offlineError("Go "+version, "the toolchain extracted in "+goroot)
+		}
+		if err := downloadGo(t.context(), version, goroot); err != nil {
+			return err
+		}
+	} else {
+		klog.V(1).Infof("Using %s from %s", version, goroot)
+	}
+
+	// GOTOOLCHAIN=local keeps go from switching to the toolchain of go.mod
+	t.env = mergeEnv(t.env, []string{
+		"PATH=" + filepath.Join(goroot, "bin") + string(os.PathListSeparator) + envValue(t.env, "PATH"),
+		"GOROOT=" + goroot,
+		"GOTOOLCHAIN=local",
+	})
+	return addMetadata(map[string]string{"go-version": version})
+}
+
+// downloadGo downloads and verifies the archive of the Go toolchain version,
+// and unpacks it into goroot.
+func downloadGo(ctx context.Context, version, goroot string) error {
+	archive := fmt.Sprintf("%s.%s-%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+	url := goDownloadURL + "/" + archive
+	klog.V(0).Infof("Downloading Go toolchain %s", archive)
+
+	archivePath := goroot + ".tar.gz"
+	if err := downloadFile(ctx, url, archivePath, 0644); err != nil {
+		return fmt.Errorf("failed to download Go toolchain %s: %v", version, err)
+	}
+	defer os.Remove(archivePath)
+
+	expected, err := fetchString(ctx, url+".sha256")
+	if err != nil {
+		return fmt.Errorf("failed to get checksum of Go toolchain %s: %v", version, err)
+	}
+	actual, err := sha256sum(archivePath)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("sha256 of %s is %s, expected %s", archive, actual, expected)
+	}
+
+	// unpack next to goroot so that a partial extraction is never used
+	tmp := goroot + ".extract"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := untar(archivePath, tmp, "go/"); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("failed to unpack %s: %v", archive, err)
+	}
+	if err := os.RemoveAll(goroot); err != nil {
+		return err
+	}
+	return os.Rename(tmp, goroot)
+}
+
+// untar unpacks the entries of the tar.gz archive at path under prefix into
+// dest, without that prefix.
+func untar(path, dest, prefix string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, ok := strings.CutPrefix(header.Name, prefix)
+		if !ok || name == "" {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path %q in archive", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package tester
+
+import (
+	"fmt"
+	"net"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// tunnelTimeout is how long the ssh tunnel may take to start listening.
+const tunnelTimeout = 30 * time.Second
+
+// startTunnel starts a SOCKS5 proxy on a local port tunneled through the
+// --ssh-bastion with ssh -D, and sets tunnelURL to it.
+func (t *Tester) startTunnel() error {
+	if t.tunnel != nil {
+		return nil
+	}
+	addr, err := freeLocalAddr()
+	if err != nil {
+		return fmt.Errorf("failed to pick a port for the ssh tunnel: %v", err)
+	}
+
+	host, port := t.SSHBastion, ""
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		host, port = host[:i], host[i+1:]
+	}
+	args := []string{"-N", "-D", addr,
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+		"-o", "BatchMode=yes",
+	}
+	if port != "" {
+		args = append(args, "-p", port)
+	}
+	args = append(args, host)
+
+	klog.V(0).Infof("Tunneling to the API server through %s", t.SSHBastion)
+	cmd := osexec.Command("ssh", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ssh tunnel: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.After(tunnelTimeout)
+	for {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		select {
+		case err := <-exited:
+			return fmt.Errorf("ssh tunnel through %s exited: %v", t.SSHBastion, err)
+		case <-deadline:
+			_ = cmd.Process.Kill()
+			return fmt.Errorf("ssh tunnel through %s not listening after %v", t.SSHBastion, tunnelTimeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	t.tunnel = cmd
+	t.tunnelURL = "socks5://" + addr
+	return nil
+}
+
+// stopTunnel stops the ssh tunnel, if any.
+func (t *Tester) stopTunnel() {
+	if t.tunnel == nil {
+		return
+	}
+	klog.V(1).Infof("Stopping ssh tunnel through %s", t.SSHBastion)
+	_ = t.tunnel.Process.Kill()
+	t.tunnel = nil
+}
+
+// freeLocalAddr returns a loopback address with a port that is free.
+func freeLocalAddr() (string, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
+
+// setProxyURL sets the proxy-url of the cluster of the current context of
+// the kubeconfig at path.
+func setProxyURL(kubectl, path, proxyURL string) error {
+	cmd := exec.Command(kubectl, "--kubeconfig="+path, "config", "view", "--minify", "--output=jsonpath={.clusters[0].name}")
+	out, err := exec.Output(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to get the current cluster of %s: %v", path, err)
+	}
+	cluster := strings.TrimSpace(string(out))
+	cmd = exec.Command(kubectl, "--kubeconfig="+path, "config", "set-cluster", cluster, "--proxy-url="+proxyURL)
+	exec.NoOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to set the proxy of cluster %q: %v", cluster, err)
+	}
+	return nil
+}
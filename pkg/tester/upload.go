@@ -0,0 +1,71 @@
+package tester
+
+import (
+	"fmt"
+	"net/url"
+	osexec "os/exec"
+	"strings"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/kubetest2/pkg/artifacts"
+	"sigs.k8s.io/kubetest2/pkg/exec"
+)
+
+// uploadArtifacts syncs the artifacts dir to the --artifact-upload bucket.
+func (t *Tester) uploadArtifacts() error {
+	tc, err := t.uploadCommand()
+	if err != nil {
+		return err
+	}
+	if _, err := osexec.LookPath(tc.path); err != nil {
+		return fmt.Errorf("%s is needed to upload artifacts to %s: %v", tc.path, t.ArtifactUpload, err)
+	}
+	klog.V(0).Infof("Uploading artifacts to %s", t.ArtifactUpload)
+	cmd := exec.Command(tc.path, tc.args...)
+	cmd.SetEnv(t.env...)
+	exec.InheritOutput(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %v", tc.path, err)
+	}
+	return nil
+}
+
+// uploadCommand returns the command that syncs the artifacts dir to the
+// --artifact-upload bucket.
+func (t *Tester) uploadCommand() (*testCommand, error) {
+	u, err := url.Parse(t.ArtifactUpload)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid --artifact-upload %q, must be gs://bucket/prefix or s3://bucket/prefix", t.ArtifactUpload)
+	}
+	dest := strings.TrimSuffix(t.ArtifactUpload, "/")
+	switch u.Scheme {
+	case "gs":
+		return &testCommand{path: "gsutil", args: []string{"-m", "rsync", "-r", artifacts.BaseDir(), dest}}, nil
+	case "s3":
+		return &testCommand{path: "aws", args: []string{"s3", "sync", "--only-show-errors", artifacts.BaseDir(), dest}}, nil
+	}
+	return nil, fmt.Errorf("unsupported --artifact-upload scheme %q, must be gs or s3", u.Scheme)
+}
+
+// artifactsURL returns the --artifacts-url, defaulting to the browser URL
+// of the --artifact-upload bucket.
+func (t *Tester) artifactsURL() string {
+	if t.ArtifactsURL != "" || t.ArtifactUpload == "" {
+		return t.ArtifactsURL
+	}
+	u, err := url.Parse(t.ArtifactUpload)
+	if err != nil {
+		return ""
+	}
+	path := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "gs":
+		return "https://console.cloud.google.com/storage/browser/" + strings.TrimSuffix(u.Host+"/"+path, "/")
+	case "s3":
+		if path == "" {
+			return "https://s3.console.aws.amazon.com/s3/buckets/" + u.Host
+		}
+		return "https://s3.console.aws.amazon.com/s3/buckets/" + u.Host + "?prefix=" + url.QueryEscape(path+"/")
+	}
+	return ""
+}